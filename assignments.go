@@ -3,76 +3,81 @@ package conditional
 import "github.com/buildkite/conditional/internal/object"
 
 type assignmentDefinition struct {
-	name  string
-	typ   valueType
-	value func(Context) object.Object
+	name        string
+	description string
+	typ         valueType
+	// nullable completes "is null ..." for values Buildkite leaves unset in
+	// some builds, such as "for builds that are not pull requests". It is
+	// empty for values that are always set.
+	nullable string
+	value    func(Context) object.Object
 }
 
 var baseAssignmentDefinitions = []assignmentDefinition{
-	{name: "build.id", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.ID) }},
-	{name: "build.state", typ: enumValueType("build state", "creating", "started", "running", "scheduled", "blocked", "passed", "failing", "failed", "canceling", "canceled", "skipped", "not_run"), value: func(ctx Context) object.Object { return stringValue(ctx.Build.State) }},
-	{name: "build.fixed", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Build.Fixed) }},
-	{name: "build.blocked_state", typ: enumValueType("build blocked state", "failed", "passed", "running"), value: func(ctx Context) object.Object { return stringValue(ctx.Build.BlockedState) }},
-	{name: "build.source", typ: enumValueType("build source", "api", "ui", "webhook", "trigger_job", "schedule", "pipeline_trigger"), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Source) }},
-	{name: "build.source_event", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(sourceEvent(ctx)) }},
-	{name: "build.source_action", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(sourceAction(ctx)) }},
-	{name: "build.branch", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Branch) }},
-	{name: "build.tag", typ: stringType(), value: func(ctx Context) object.Object { return presenceStringValue(ctx.Build.Tag) }},
-	{name: "build.message", typ: stringType(), value: func(ctx Context) object.Object { return presenceStringValue(ctx.Build.Message) }},
-	{name: "build.commit", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Commit) }},
-	{name: "build.number", typ: numberType(), value: func(ctx Context) object.Object { return intValue(ctx.Build.Number) }},
-	{name: "build.creator.id", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Creator.ID) }},
-	{name: "build.creator.name", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Creator.Name) }},
-	{name: "build.creator.email", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Creator.Email) }},
-	{name: "build.creator.teams", typ: stringArrayType(), value: func(ctx Context) object.Object { return stringArrayValue(ctx.Build.Creator.Teams) }},
-	{name: "build.creator.verified", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Build.Creator.Verified) }},
-	{name: "build.author.id", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Author.ID) }},
-	{name: "build.author.name", typ: stringType(), value: func(ctx Context) object.Object { return presenceStringValue(ctx.Build.Author.Name) }},
-	{name: "build.author.email", typ: stringType(), value: func(ctx Context) object.Object { return presenceStringValue(ctx.Build.Author.Email) }},
-	{name: "build.author.teams", typ: stringArrayType(), value: func(ctx Context) object.Object { return stringArrayValue(ctx.Build.Author.Teams) }},
-	{name: "build.scm.author.name", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.SCM.AuthorName) }},
-	{name: "build.scm.author.email", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.SCM.AuthorEmail) }},
-	{name: "build.scm.committer.name", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.SCM.CommitterName) }},
-	{name: "build.scm.committer.email", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.SCM.CommitterEmail) }},
-	{name: "build.pull_request.id", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.PullRequest.ID) }},
-	{name: "build.pull_request.base_branch", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.PullRequest.BaseBranch) }},
-	{name: "build.pull_request.draft", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Build.PullRequest.Draft) }},
-	{name: "build.pull_request.label", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(pullRequestLabel(ctx)) }},
-	{name: "build.pull_request.labels", typ: stringArrayType(), value: func(ctx Context) object.Object { return stringArrayValue(ctx.Build.PullRequest.Labels) }},
-	{name: "build.pull_request.repository", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.PullRequest.Repository) }},
-	{name: "build.pull_request.repository.fork", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Build.PullRequest.RepositoryFork) }},
-	{name: "build.merge_queue.base_branch", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.MergeQueue.BaseBranch) }},
-	{name: "build.merge_queue.base_commit", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.MergeQueue.BaseCommit) }},
-	{name: "pipeline.id", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Pipeline.ID) }},
-	{name: "pipeline.slug", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Pipeline.Slug) }},
-	{name: "pipeline.default_branch", typ: stringType(), value: func(ctx Context) object.Object { return presenceStringValue(ctx.Pipeline.DefaultBranch) }},
-	{name: "pipeline.repository", typ: stringType(), value: func(ctx Context) object.Object { return presenceStringValue(ctx.Pipeline.Repository) }},
+	{name: "build.id", description: "The UUID of the build.", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.ID) }},
+	{name: "build.state", description: "The state the build is in.", typ: enumValueType("build state", "creating", "started", "running", "scheduled", "blocked", "passed", "failing", "failed", "canceling", "canceled", "skipped", "not_run"), value: func(ctx Context) object.Object { return stringValue(ctx.Build.State) }},
+	{name: "build.fixed", description: "Whether the build passed after the previous build on the same branch failed.", typ: boolType(), nullable: "until the build finishes", value: func(ctx Context) object.Object { return boolValue(ctx.Build.Fixed) }},
+	{name: "build.blocked_state", description: "The state of a blocked build.", typ: enumValueType("build blocked state", "failed", "passed", "running"), nullable: "when the build is not blocked", value: func(ctx Context) object.Object { return stringValue(ctx.Build.BlockedState) }},
+	{name: "build.source", description: "Where the build was created from.", typ: enumValueType("build source", "api", "ui", "webhook", "trigger_job", "schedule", "pipeline_trigger"), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Source) }},
+	{name: "build.source_event", description: "The webhook event that created the build, such as \"push\" or \"pull_request\".", typ: stringType(), nullable: "for builds that were not created by a webhook", value: func(ctx Context) object.Object { return stringValue(sourceEvent(ctx)) }},
+	{name: "build.source_action", description: "The action of the webhook event that created the build, such as \"opened\" or \"labeled\".", typ: stringType(), nullable: "for builds that were not created by a webhook", value: func(ctx Context) object.Object { return stringValue(sourceAction(ctx)) }},
+	{name: "build.branch", description: "The branch the build is for.", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Branch) }},
+	{name: "build.tag", description: "The tag the build is for.", typ: stringType(), nullable: "for builds that are not for a tag", value: func(ctx Context) object.Object { return presenceStringValue(ctx.Build.Tag) }},
+	{name: "build.message", description: "The message of the build, usually the commit message.", typ: stringType(), nullable: "when the build message is blank", value: func(ctx Context) object.Object { return presenceStringValue(ctx.Build.Message) }},
+	{name: "build.commit", description: "The commit the build is for.", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Build.Commit) }},
+	{name: "build.number", description: "The number of the build.", typ: numberType(), value: func(ctx Context) object.Object { return intValue(ctx.Build.Number) }},
+	{name: "build.creator.id", description: "The UUID of the Buildkite user who created the build.", typ: stringType(), nullable: "when the build was not created by a Buildkite user", value: func(ctx Context) object.Object { return stringValue(ctx.Build.Creator.ID) }},
+	{name: "build.creator.name", description: "The name of the Buildkite user who created the build.", typ: stringType(), nullable: "when the build was not created by a Buildkite user", value: func(ctx Context) object.Object { return stringValue(ctx.Build.Creator.Name) }},
+	{name: "build.creator.email", description: "The email address of the Buildkite user who created the build.", typ: stringType(), nullable: "when the build was not created by a Buildkite user", value: func(ctx Context) object.Object { return stringValue(ctx.Build.Creator.Email) }},
+	{name: "build.creator.teams", description: "The slugs of the teams the build creator is a member of.", typ: stringArrayType(), nullable: "when the build was not created by a Buildkite user", value: func(ctx Context) object.Object { return stringArrayValue(ctx.Build.Creator.Teams) }},
+	{name: "build.creator.verified", description: "Whether the build creator is a verified Buildkite user.", typ: boolType(), nullable: "when the build was not created by a Buildkite user", value: func(ctx Context) object.Object { return boolValue(ctx.Build.Creator.Verified) }},
+	{name: "build.author.id", description: "The UUID of the Buildkite user who authored the commit.", typ: stringType(), nullable: "when the build has no Buildkite author", value: func(ctx Context) object.Object { return stringValue(ctx.Build.Author.ID) }},
+	{name: "build.author.name", description: "The name of the Buildkite user who authored the commit.", typ: stringType(), nullable: "when the build has no Buildkite author", value: func(ctx Context) object.Object { return presenceStringValue(ctx.Build.Author.Name) }},
+	{name: "build.author.email", description: "The email address of the Buildkite user who authored the commit.", typ: stringType(), nullable: "when the build has no Buildkite author", value: func(ctx Context) object.Object { return presenceStringValue(ctx.Build.Author.Email) }},
+	{name: "build.author.teams", description: "The slugs of the teams the build author is a member of.", typ: stringArrayType(), nullable: "when the build has no Buildkite author", value: func(ctx Context) object.Object { return stringArrayValue(ctx.Build.Author.Teams) }},
+	{name: "build.scm.author.name", description: "The name of the commit author from source control.", typ: stringType(), nullable: "when the commit details are unknown", value: func(ctx Context) object.Object { return stringValue(ctx.Build.SCM.AuthorName) }},
+	{name: "build.scm.author.email", description: "The email address of the commit author from source control.", typ: stringType(), nullable: "when the commit details are unknown", value: func(ctx Context) object.Object { return stringValue(ctx.Build.SCM.AuthorEmail) }},
+	{name: "build.scm.committer.name", description: "The name of the committer from source control.", typ: stringType(), nullable: "when the commit details are unknown", value: func(ctx Context) object.Object { return stringValue(ctx.Build.SCM.CommitterName) }},
+	{name: "build.scm.committer.email", description: "The email address of the committer from source control.", typ: stringType(), nullable: "when the commit details are unknown", value: func(ctx Context) object.Object { return stringValue(ctx.Build.SCM.CommitterEmail) }},
+	{name: "build.pull_request.id", description: "The number of the pull request.", typ: stringType(), nullable: "for builds that are not pull requests", value: func(ctx Context) object.Object { return stringValue(ctx.Build.PullRequest.ID) }},
+	{name: "build.pull_request.base_branch", description: "The branch the pull request targets.", typ: stringType(), nullable: "for builds that are not pull requests", value: func(ctx Context) object.Object { return stringValue(ctx.Build.PullRequest.BaseBranch) }},
+	{name: "build.pull_request.draft", description: "Whether the pull request is a draft.", typ: boolType(), nullable: "for builds that are not pull requests", value: func(ctx Context) object.Object { return boolValue(ctx.Build.PullRequest.Draft) }},
+	{name: "build.pull_request.label", description: "The label added or removed by a labeled or unlabeled pull request event.", typ: stringType(), nullable: "for builds that are not pull requests", value: func(ctx Context) object.Object { return stringValue(pullRequestLabel(ctx)) }},
+	{name: "build.pull_request.labels", description: "The labels on the pull request.", typ: stringArrayType(), nullable: "for builds that are not pull requests", value: func(ctx Context) object.Object { return stringArrayValue(ctx.Build.PullRequest.Labels) }},
+	{name: "build.pull_request.repository", description: "The repository URL of the pull request.", typ: stringType(), nullable: "for builds that are not pull requests", value: func(ctx Context) object.Object { return stringValue(ctx.Build.PullRequest.Repository) }},
+	{name: "build.pull_request.repository.fork", description: "Whether the pull request comes from a fork.", typ: boolType(), nullable: "for builds that are not pull requests", value: func(ctx Context) object.Object { return boolValue(ctx.Build.PullRequest.RepositoryFork) }},
+	{name: "build.merge_queue.base_branch", description: "The branch the merge queue targets.", typ: stringType(), nullable: "for builds that are not in a merge queue", value: func(ctx Context) object.Object { return stringValue(ctx.Build.MergeQueue.BaseBranch) }},
+	{name: "build.merge_queue.base_commit", description: "The commit the merge queue is based on.", typ: stringType(), nullable: "for builds that are not in a merge queue", value: func(ctx Context) object.Object { return stringValue(ctx.Build.MergeQueue.BaseCommit) }},
+	{name: "pipeline.id", description: "The UUID of the pipeline.", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Pipeline.ID) }},
+	{name: "pipeline.slug", description: "The slug of the pipeline.", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Pipeline.Slug) }},
+	{name: "pipeline.default_branch", description: "The default branch of the pipeline.", typ: stringType(), nullable: "when the pipeline has no default branch", value: func(ctx Context) object.Object { return presenceStringValue(ctx.Pipeline.DefaultBranch) }},
+	{name: "pipeline.repository", description: "The repository URL of the pipeline.", typ: stringType(), nullable: "when the pipeline has no repository", value: func(ctx Context) object.Object { return presenceStringValue(ctx.Pipeline.Repository) }},
 	// Upstream Build::Condition exposes these in its base assignment table,
 	// even though the public docs describe them as notification variables.
-	{name: "pipeline.started_passing", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Pipeline.StartedPassing) }},
-	{name: "pipeline.started_failing", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Pipeline.StartedFailing) }},
-	{name: "pipeline.next_finished_build_exists", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Pipeline.NextFinishedBuildExists) }},
-	{name: "organization.id", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Organization.ID) }},
-	{name: "organization.slug", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Organization.Slug) }},
+	{name: "pipeline.started_passing", description: "Whether the build started the pipeline passing after it was failing.", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Pipeline.StartedPassing) }},
+	{name: "pipeline.started_failing", description: "Whether the build started the pipeline failing after it was passing.", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Pipeline.StartedFailing) }},
+	{name: "pipeline.next_finished_build_exists", description: "Whether a later build of the pipeline has already finished.", typ: boolType(), value: func(ctx Context) object.Object { return boolValue(ctx.Pipeline.NextFinishedBuildExists) }},
+	{name: "organization.id", description: "The UUID of the organization.", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Organization.ID) }},
+	{name: "organization.slug", description: "The slug of the organization.", typ: stringType(), value: func(ctx Context) object.Object { return stringValue(ctx.Organization.Slug) }},
 }
 
 var stepAssignmentDefinitions = []assignmentDefinition{
-	{name: "step.id", typ: stringType(), value: func(ctx Context) object.Object {
+	{name: "step.id", description: "The UUID of the step.", typ: stringType(), value: func(ctx Context) object.Object {
 		return stringValue(stepString(ctx.Step, func(step *Step) *string { return step.ID }))
 	}},
-	{name: "step.key", typ: stringType(), value: func(ctx Context) object.Object {
+	{name: "step.key", description: "The key of the step.", typ: stringType(), nullable: "when the step has no key", value: func(ctx Context) object.Object {
 		return stringValue(stepString(ctx.Step, func(step *Step) *string { return step.Key }))
 	}},
-	{name: "step.type", typ: enumValueType("step type", "command", "wait", "input", "trigger", "group"), value: func(ctx Context) object.Object {
+	{name: "step.type", description: "The type of the step.", typ: enumValueType("step type", "command", "wait", "input", "trigger", "group"), value: func(ctx Context) object.Object {
 		return stringValue(stepString(ctx.Step, func(step *Step) *string { return step.Type }))
 	}},
-	{name: "step.label", typ: stringType(), value: func(ctx Context) object.Object {
+	{name: "step.label", description: "The label of the step.", typ: stringType(), nullable: "when the step has no label", value: func(ctx Context) object.Object {
 		return stringValue(stepString(ctx.Step, func(step *Step) *string { return step.Label }))
 	}},
-	{name: "step.state", typ: enumValueType("step state", "ignored", "waiting_for_dependencies", "ready", "waiting_for_input", "running", "failing", "canceled", "finished"), value: func(ctx Context) object.Object {
+	{name: "step.state", description: "The state the step is in.", typ: enumValueType("step state", "ignored", "waiting_for_dependencies", "ready", "waiting_for_input", "running", "failing", "canceled", "finished"), value: func(ctx Context) object.Object {
 		return stringValue(stepString(ctx.Step, func(step *Step) *string { return step.State }))
 	}},
-	{name: "step.outcome", typ: enumValueType("step outcome", "neutral", "passed", "soft_failed", "hard_failed", "errored"), value: func(ctx Context) object.Object {
+	{name: "step.outcome", description: "The outcome of the finished step.", typ: enumValueType("step outcome", "neutral", "passed", "soft_failed", "hard_failed", "errored"), nullable: "until the step finishes", value: func(ctx Context) object.Object {
 		return stringValue(stepString(ctx.Step, func(step *Step) *string { return step.Outcome }))
 	}},
 }
@@ -87,6 +92,17 @@ func assignmentDefinitions(ctx Context) []assignmentDefinition {
 	return append(definitions, stepAssignmentDefinitions...)
 }
 
+// nullableVariables maps each nullable assignment in ctx to when it is null.
+func nullableVariables(ctx Context) map[string]string {
+	nullable := map[string]string{}
+	for _, definition := range assignmentDefinitions(ctx) {
+		if definition.nullable != "" {
+			nullable[definition.name] = definition.nullable
+		}
+	}
+	return nullable
+}
+
 func flatAssignments(ctx Context) object.Struct {
 	definitions := assignmentDefinitions(ctx)
 	assignments := make(object.Struct, len(definitions))
@@ -0,0 +1,33 @@
+package conditional
+
+import "testing"
+
+const regexHeavyExpression = `build.branch =~ /^release\/v[0-9]+\.[0-9]+$/ || ` +
+	`build.branch =~ /^hotfix\/[a-z0-9-]+$/ || ` +
+	`build.message =~ /\[deploy( to [a-z]+)?\]/i`
+
+func BenchmarkEvaluateRegexHeavy(bench *testing.B) {
+	ctx := Context{Build: Build{Branch: str("feature/cache"), Message: str("[deploy to staging]")}}
+
+	for _, bm := range []struct {
+		name   string
+		option Option
+	}{
+		{name: "cached", option: WithRegexCache(16)},
+		{name: "uncached", option: WithRegexCache(0)},
+	} {
+		bench.Run(bm.name, func(bench *testing.B) {
+			evaluator, err := NewEvaluator(bm.option)
+			if err != nil {
+				bench.Fatalf("NewEvaluator returned error: %v", err)
+			}
+			bench.ResetTimer()
+
+			for i := 0; i < bench.N; i++ {
+				if _, err := evaluator.Evaluate(regexHeavyExpression, ctx); err != nil {
+					bench.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	conditional "github.com/buildkite/conditional"
+)
+
+// lintFinding is a diagnostic in -format json output.
+type lintFinding struct {
+	Expression    string `json:"expression"`
+	Severity      string `json:"severity"`
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+	Subexpression string `json:"subexpression,omitempty"`
+}
+
+// runLint lints each expression given as an argument, or each non-empty line
+// of stdin when there are none. It returns 1 when any warning or error is
+// reported and 2 on usage errors.
+func runLint(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	entryPoint := flags.String("entry-point", string(conditional.EntryPointBuildCondition), "entry point to lint against")
+	dialect := flags.String("dialect", string(conditional.BuildkiteServer), "conditional dialect")
+	format := flags.String("format", "text", "output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "ERROR: unknown format %q, want text or json\n", *format)
+		return 2
+	}
+
+	evaluator, err := conditional.NewEvaluator(conditional.WithDialect(conditional.Dialect(*dialect)))
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR: %s\n", err)
+		return 2
+	}
+	ctx := conditional.Context{EntryPoint: conditional.EntryPoint(*entryPoint)}
+
+	expressions := flags.Args()
+	if len(expressions) == 0 {
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				expressions = append(expressions, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(stderr, "ERROR: %s\n", err)
+			return 2
+		}
+	}
+
+	status := 0
+	findings := []lintFinding{}
+	for _, expression := range expressions {
+		for _, diagnostic := range evaluator.Lint(expression, ctx) {
+			if diagnostic.Severity != conditional.SeverityInfo {
+				status = 1
+			}
+			if *format == "json" {
+				findings = append(findings, lintFinding{
+					Expression:    expression,
+					Severity:      string(diagnostic.Severity),
+					Code:          diagnostic.Code,
+					Message:       diagnostic.Message,
+					Subexpression: diagnostic.Expression,
+				})
+				continue
+			}
+			fmt.Fprintf(stdout, "%s: %s [%s]\n", expression, diagnostic, diagnostic.Code)
+		}
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			fmt.Fprintf(stderr, "ERROR: %s\n", err)
+			return 2
+		}
+	}
+	return status
+}
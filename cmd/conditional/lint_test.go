@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunLint(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantStatus int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "clean argument",
+			args:       []string{`build.branch == "main"`},
+			wantStatus: 0,
+			wantStdout: "",
+		},
+		{
+			name:       "info only",
+			args:       []string{`build.branch == "release-$VERSION"`},
+			wantStatus: 0,
+			wantStdout: `build.branch == "release-$VERSION": info: "release-$VERSION" expands $VERSION from the environment at runtime; use single quotes if that is not intended [string-template]` + "\n",
+		},
+		{
+			name:       "warning argument",
+			args:       []string{`!!build.pull_request.draft`},
+			wantStatus: 1,
+			wantStdout: "!!build.pull_request.draft: warning: double negation `(!(!build.pull_request.draft))` can be written as `build.pull_request.draft` [double-negation]\n",
+		},
+		{
+			name:       "error argument",
+			args:       []string{`nope !=`},
+			wantStatus: 1,
+			wantStdout: "nope !=: error: no prefix parse function for EOF found [invalid]\n",
+		},
+		{
+			name:       "stdin lines",
+			stdin:      "build.branch == \"main\"\n\nnope !=\n",
+			wantStatus: 1,
+			wantStdout: "nope !=: error: no prefix parse function for EOF found [invalid]\n",
+		},
+		{
+			name:       "arguments take precedence over stdin",
+			args:       []string{`build.branch == "main"`},
+			stdin:      "nope !=\n",
+			wantStatus: 0,
+			wantStdout: "",
+		},
+		{
+			name:       "json clean",
+			args:       []string{"-format", "json", `build.branch == "main"`},
+			wantStatus: 0,
+			wantStdout: "[]\n",
+		},
+		{
+			name:       "json error from stdin",
+			args:       []string{"-format", "json"},
+			stdin:      "nope !=\n",
+			wantStatus: 1,
+			wantStdout: `[
+  {
+    "expression": "nope !=",
+    "severity": "error",
+    "code": "invalid",
+    "message": "no prefix parse function for EOF found"
+  }
+]
+`,
+		},
+		{
+			name:       "json warning",
+			args:       []string{"-format=json", `build.branch == "a" && build.branch == "b"`},
+			wantStatus: 1,
+			wantStdout: `[
+  {
+    "expression": "build.branch == \"a\" && build.branch == \"b\"",
+    "severity": "warning",
+    "code": "always-false",
+    "message": "` + "`" + `((build.branch == \"a\") && (build.branch == \"b\"))` + "`" + ` is always false",
+    "subexpression": "((build.branch == \"a\") && (build.branch == \"b\"))"
+  }
+]
+`,
+		},
+		{
+			name:       "unknown format",
+			args:       []string{"-format", "xml", `build.branch == "main"`},
+			wantStatus: 2,
+			wantStderr: `ERROR: unknown format "xml", want text or json` + "\n",
+		},
+		{
+			name:       "unknown dialect",
+			args:       []string{"-dialect", "ruby", `build.branch == "main"`},
+			wantStatus: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			status := runLint(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+			if status != tt.wantStatus {
+				t.Fatalf("runLint status = %d, want %d (stderr %q)", status, tt.wantStatus, stderr.String())
+			}
+			if stdout.String() != tt.wantStdout {
+				t.Fatalf("runLint stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if tt.wantStderr != "" && stderr.String() != tt.wantStderr {
+				t.Fatalf("runLint stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
@@ -1,13 +1,46 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	conditional "github.com/buildkite/conditional"
 	"github.com/buildkite/conditional/internal/repl"
 )
 
+// defaultRedactions names the environment variables whose values the REPL
+// replaces with [REDACTED] in error output unless -redact is given.
+var defaultRedactions = []string{
+	"*_TOKEN",
+	"*_SECRET",
+	"*_SECRET_*",
+	"*_PASSWORD",
+	"*_KEY",
+	"BUILDKITE_GITHUB_DEPLOYMENT_PAYLOAD",
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	flags := flag.NewFlagSet("conditional", flag.ExitOnError)
+	redact := flags.String("redact", strings.Join(defaultRedactions, ","), "comma-separated environment name patterns to redact from errors, or empty for none")
+	flags.Parse(os.Args[1:])
+
 	fmt.Println("Buildkite condition evaluator")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, conditional.WithRedaction(redactionPatterns(*redact)...))
+}
+
+// redactionPatterns splits a -redact value into its patterns.
+func redactionPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
 }
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRedactionPatterns(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "", want: nil},
+		{value: "*_TOKEN", want: []string{"*_TOKEN"}},
+		{value: " *_TOKEN, ,*_SECRET ", want: []string{"*_TOKEN", "*_SECRET"}},
+	}
+
+	for _, tt := range tests {
+		if got := redactionPatterns(tt.value); !slices.Equal(got, tt.want) {
+			t.Fatalf("redactionPatterns(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package conditional
+
+import (
+	"strconv"
+
+	"github.com/buildkite/conditional/internal/ast"
+)
+
+// maxConditionAssignments bounds the candidate assignments constantCondition
+// enumerates before giving up on an expression.
+const maxConditionAssignments = 1 << 12
+
+const (
+	nullCandidate  = "null"
+	otherCandidate = "\x00other"
+)
+
+// condition is a boolean formula over comparisons between subjects, such as
+// build.branch or env("FOO"), and static literals. Anything else is an opaque
+// term whose truth value is unknown but shared by identical expressions.
+type condition interface {
+	holds(assignment conditionAssignment) bool
+}
+
+type conditionAssignment struct {
+	subjects map[string]string
+	opaque   map[string]bool
+}
+
+type andCondition struct{ left, right condition }
+
+func (c andCondition) holds(a conditionAssignment) bool { return c.left.holds(a) && c.right.holds(a) }
+
+type orCondition struct{ left, right condition }
+
+func (c orCondition) holds(a conditionAssignment) bool { return c.left.holds(a) || c.right.holds(a) }
+
+type notCondition struct{ inner condition }
+
+func (c notCondition) holds(a conditionAssignment) bool { return !c.inner.holds(a) }
+
+type constantTerm bool
+
+func (c constantTerm) holds(conditionAssignment) bool { return bool(c) }
+
+type opaqueTerm string
+
+func (c opaqueTerm) holds(a conditionAssignment) bool { return a.opaque[string(c)] }
+
+// memberTerm holds when the subject's value is one of values.
+type memberTerm struct {
+	subject string
+	values  map[string]struct{}
+}
+
+func (c memberTerm) holds(a conditionAssignment) bool {
+	_, ok := c.values[a.subjects[c.subject]]
+	return ok
+}
+
+type conditionModel struct {
+	checker      typeChecker
+	domains      map[string][]string
+	domainValues map[string]map[string]struct{}
+	subjects     []string
+	opaque       []string
+	opaqueSeen   map[string]struct{}
+}
+
+// constantCondition reports whether expr evaluates to the same boolean for
+// every value its compared subjects can take, and what that result is. A
+// subject typed as an enumeration can only take its members or null; any
+// other subject can also take a value that no literal mentions.
+func constantCondition(expr ast.Expression, checker typeChecker) (bool, bool) {
+	model := &conditionModel{
+		checker:      checker,
+		domains:      map[string][]string{},
+		domainValues: map[string]map[string]struct{}{},
+		opaqueSeen:   map[string]struct{}{},
+	}
+	formula := model.build(expr)
+	return model.constant(formula)
+}
+
+func (m *conditionModel) build(expr ast.Expression) condition {
+	switch expr := expr.(type) {
+	case *ast.Boolean:
+		return constantTerm(expr.Value)
+	case *ast.Null:
+		return constantTerm(false)
+	case *ast.PrefixExpression:
+		if expr.Operator == "!" {
+			return notCondition{inner: m.build(expr.Right)}
+		}
+	case *ast.InfixExpression:
+		switch expr.Operator {
+		case "&&":
+			return andCondition{left: m.build(expr.Left), right: m.build(expr.Right)}
+		case "||":
+			return orCondition{left: m.build(expr.Left), right: m.build(expr.Right)}
+		case "==", "!=":
+			if term, ok := m.comparison(expr.Left, expr.Right); ok {
+				if expr.Operator == "!=" {
+					return notCondition{inner: term}
+				}
+				return term
+			}
+		case "in":
+			if term, ok := m.membership(expr.Left, expr.Right); ok {
+				return term
+			}
+		}
+	}
+	return m.opaqueTerm(expr)
+}
+
+func (m *conditionModel) comparison(left, right ast.Expression) (condition, bool) {
+	subject, literal := left, right
+	if _, ok := literalCandidate(subject); ok {
+		subject, literal = right, left
+	}
+	if !isConditionSubject(subject) {
+		return nil, false
+	}
+	value, ok := literalCandidate(literal)
+	if !ok {
+		return nil, false
+	}
+	return m.memberTerm(subject, value), true
+}
+
+func (m *conditionModel) membership(left, right ast.Expression) (condition, bool) {
+	array, ok := right.(*ast.ArrayLiteral)
+	if !ok || !isConditionSubject(left) {
+		return nil, false
+	}
+	values := make([]string, 0, len(array.Elements))
+	for _, element := range array.Elements {
+		value, ok := literalCandidate(element)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return m.memberTerm(left, values...), true
+}
+
+func (m *conditionModel) memberTerm(subject ast.Expression, values ...string) condition {
+	key := subject.String()
+	if _, ok := m.domainValues[key]; !ok {
+		m.subjects = append(m.subjects, key)
+		m.domainValues[key] = map[string]struct{}{}
+		m.addCandidate(key, nullCandidate)
+		if enum := m.enum(subject); enum != nil {
+			for _, member := range enum.members() {
+				m.addCandidate(key, "string:"+member)
+			}
+		} else {
+			m.addCandidate(key, otherCandidate)
+		}
+	}
+
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		m.addCandidate(key, value)
+		set[value] = struct{}{}
+	}
+	return memberTerm{subject: key, values: set}
+}
+
+func (m *conditionModel) enum(subject ast.Expression) *enumType {
+	typ, err := m.checker.check(subject)
+	if err != nil {
+		return nil
+	}
+	return typ.enum
+}
+
+func (m *conditionModel) addCandidate(subject string, value string) {
+	if _, ok := m.domainValues[subject][value]; ok {
+		return
+	}
+	m.domainValues[subject][value] = struct{}{}
+	m.domains[subject] = append(m.domains[subject], value)
+}
+
+func (m *conditionModel) opaqueTerm(expr ast.Expression) condition {
+	key := expr.String()
+	if _, ok := m.opaqueSeen[key]; !ok {
+		m.opaqueSeen[key] = struct{}{}
+		m.opaque = append(m.opaque, key)
+	}
+	return opaqueTerm(key)
+}
+
+// constant enumerates every combination of subject candidates and opaque
+// truth values. It gives up when there are too many combinations.
+func (m *conditionModel) constant(formula condition) (bool, bool) {
+	total := 1
+	for _, subject := range m.subjects {
+		total *= len(m.domains[subject])
+		if total > maxConditionAssignments {
+			return false, false
+		}
+	}
+	for range m.opaque {
+		total *= 2
+		if total > maxConditionAssignments {
+			return false, false
+		}
+	}
+
+	assignment := conditionAssignment{
+		subjects: make(map[string]string, len(m.subjects)),
+		opaque:   make(map[string]bool, len(m.opaque)),
+	}
+	sawTrue, sawFalse := false, false
+	for i := 0; i < total; i++ {
+		rest := i
+		for _, subject := range m.subjects {
+			domain := m.domains[subject]
+			assignment.subjects[subject] = domain[rest%len(domain)]
+			rest /= len(domain)
+		}
+		for _, key := range m.opaque {
+			assignment.opaque[key] = rest%2 == 1
+			rest /= 2
+		}
+
+		if formula.holds(assignment) {
+			sawTrue = true
+		} else {
+			sawFalse = true
+		}
+		if sawTrue && sawFalse {
+			return false, false
+		}
+	}
+	return sawTrue, true
+}
+
+func isConditionSubject(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.Identifier, *ast.CallExpression, *ast.ShellExpansion:
+		return true
+	default:
+		return false
+	}
+}
+
+// literalCandidate returns a candidate key for a static literal. Keys are
+// prefixed by type so that 1 and "1" stay distinct.
+func literalCandidate(expr ast.Expression) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.Null:
+		return nullCandidate, true
+	case *ast.Boolean:
+		return "boolean:" + strconv.FormatBool(expr.Value), true
+	case *ast.IntegerLiteral:
+		return "number:" + strconv.FormatInt(expr.Value, 10), true
+	case *ast.StringLiteral:
+		if runtimeStringLiteral(expr) {
+			return "", false
+		}
+		return "string:" + expr.Value, true
+	default:
+		return "", false
+	}
+}
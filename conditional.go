@@ -11,6 +11,7 @@ import (
 	"github.com/buildkite/conditional/internal/lexer"
 	"github.com/buildkite/conditional/internal/object"
 	"github.com/buildkite/conditional/internal/parser"
+	"github.com/buildkite/conditional/internal/regex"
 )
 
 // Validate parses expression for the selected Buildkite context.
@@ -44,12 +45,15 @@ func validate(expression string, ctx Context, entryPoint EntryPoint, options opt
 		return nil
 	}
 
-	expr, err := parse(expression)
+	expr, err := parse(expression, options)
 	if err != nil {
 		return err
 	}
 	ctx.EntryPoint = entryPoint
-	return validateExpression(expr, ctx, options)
+	if err := validateExpression(expr, ctx, options); err != nil {
+		return err
+	}
+	return validateExpansions(expr)
 }
 
 func evaluateWithOptions(expression string, ctx Context, entryPoint EntryPoint, options optionSet) (bool, error) {
@@ -65,7 +69,7 @@ func evaluateWithOptions(expression string, ctx Context, entryPoint EntryPoint,
 }
 
 func evaluate(expression string, ctx Context, options optionSet) (bool, error) {
-	expr, err := parse(expression)
+	expr, err := parse(expression, options)
 	if err != nil {
 		return false, err
 	}
@@ -73,14 +77,19 @@ func evaluate(expression string, ctx Context, options optionSet) (bool, error) {
 		return false, err
 	}
 
-	result := evaluator.Eval(expr, buildScope(ctx, options))
+	scope := buildScope(ctx, options)
+	result := evaluator.Eval(expr, scope)
 	switch result := result.(type) {
 	case *object.Boolean:
 		return result.Value, nil
 	case *object.Null:
 		return false, nil
 	case *object.Error:
-		return false, &Error{Kind: ErrorKindEvaluation, Message: result.Message}
+		kind := ErrorKindEvaluation
+		if errors.Is(result.Cause, regex.ErrMatchTimeout) {
+			kind = ErrorKindTimeout
+		}
+		return false, scope.env.redactor.redactError(&Error{Kind: kind, Message: result.Message, Cause: result.Cause})
 	default:
 		return false, &Error{
 			Kind:    ErrorKindResult,
@@ -89,12 +98,29 @@ func evaluate(expression string, ctx Context, options optionSet) (bool, error) {
 	}
 }
 
-func parse(expression string) (ast.Expression, error) {
-	l := lexer.New(expression)
-	p := parser.New(l)
+func parse(expression string, options optionSet) (ast.Expression, error) {
+	if err := options.checkExpressionLength(expression); err != nil {
+		return nil, err
+	}
+	l := lexer.New(expression, options.lexerOptions()...)
+	p := parser.New(l, options.parserOptions()...)
 	expr := p.Parse()
 
 	if errs := p.Errors(); len(errs) > 0 {
+		if err := checkParseLimits(errs); err != nil {
+			return nil, err
+		}
+		if !options.extended() {
+			if err := extendedFlagError(errs); err != nil {
+				return nil, err
+			}
+			if err := unsupportedSyntaxError(expression, options); err != nil {
+				return nil, err
+			}
+		}
+		for i, err := range errs {
+			errs[i] = regexFeatureError(err)
+		}
 		return nil, &Error{
 			Kind:    ErrorKindParse,
 			Message: joinErrorMessages(errs),
@@ -104,6 +130,9 @@ func parse(expression string) (ast.Expression, error) {
 	if expr == nil {
 		return nil, &Error{Kind: ErrorKindParse, Message: "empty expression"}
 	}
+	if err := options.checkTreeLimits(expr); err != nil {
+		return nil, err
+	}
 
 	return expr, nil
 }
@@ -118,15 +147,25 @@ func joinErrorMessages(errs []error) string {
 
 func validateExpression(expr ast.Expression, ctx Context, options optionSet) error {
 	entryPoint := ctx.EntryPoint
-	if !stepAllowed(entryPoint) && referencesRoot(expr, "step") {
-		return &Error{
-			Kind:    ErrorKindValidation,
-			Message: fmt.Sprintf("step variables are not available for entry point %q", entryPoint),
+	if name := rootReference(expr, "step"); name != "" && !stepAllowed(entryPoint) {
+		message := fmt.Sprintf("step variables are not available for entry point %q", entryPoint)
+		if hint := unavailableHint(name, entryPoint); hint != "" {
+			message += "; " + hint
 		}
+		return &Error{Kind: ErrorKindValidation, Message: message}
+	}
+	if err := validateDialect(expr, options); err != nil {
+		return err
 	}
 	if err := validateEnvCalls(expr); err != nil {
 		return err
 	}
+	if err := validateEnvPolicy(expr, options); err != nil {
+		return err
+	}
+	if err := validateStringTemplates(expr, options); err != nil {
+		return err
+	}
 	return typeCheckExpression(expr, ctx, options)
 }
 
@@ -168,20 +207,7 @@ func validateEnvCalls(expr ast.Expression) error {
 						Message: "Argument to `env` should be an environment variable name",
 					}
 				case unsupportedBuildkiteEnv(arg.Value):
-					if suggestion := suggestBuildkiteEnv(arg.Value); suggestion != "" {
-						return &Error{
-							Kind: ErrorKindValidation,
-							Message: fmt.Sprintf(
-								"%q is not a valid environment variable - did you mean %q?",
-								arg.Value,
-								suggestion,
-							),
-						}
-					}
-					return &Error{
-						Kind:    ErrorKindValidation,
-						Message: unsupportedBuildkiteEnvMessage(arg.Value),
-					}
+					return unsupportedEnvError(arg.Value)
 				}
 			}
 		}
@@ -201,55 +227,84 @@ func validateEnvCalls(expr ast.Expression) error {
 	return nil
 }
 
-func referencesRoot(expr ast.Expression, root string) bool {
+// unsupportedEnvError rejects reading the BUILDKITE_* variable name, which
+// conditionals cannot see, suggesting a supported name when one is close.
+func unsupportedEnvError(name string) *Error {
+	if suggestion := suggestBuildkiteEnv(name); suggestion != "" {
+		return &Error{
+			Kind:    ErrorKindValidation,
+			Message: fmt.Sprintf("%q is not a valid environment variable - did you mean %q?", name, suggestion),
+		}
+	}
+	return &Error{Kind: ErrorKindValidation, Message: unsupportedBuildkiteEnvMessage(name)}
+}
+
+// rootReference returns the first identifier or function name in expr under
+// root, such as step.key for root step, or "" when there is none.
+func rootReference(expr ast.Expression, root string) string {
 	switch expr := expr.(type) {
 	case *ast.Identifier:
-		return expr.Value == root || strings.HasPrefix(expr.Value, root+".")
-	case *ast.PrefixExpression:
-		return referencesRoot(expr.Right, root)
-	case *ast.ConditionalExpression:
-		return referencesRoot(expr.Condition, root) ||
-			referencesRoot(expr.Consequence, root) ||
-			referencesRoot(expr.Alternative, root)
-	case *ast.InfixExpression:
-		return referencesRoot(expr.Left, root) || referencesRoot(expr.Right, root)
+		if expr.Value == root || strings.HasPrefix(expr.Value, root+".") {
+			return expr.Value
+		}
+		return ""
 	case *ast.CallExpression:
 		if expr.Function == root || strings.HasPrefix(expr.Function, root+".") {
-			return true
-		}
-		for _, arg := range expr.Arguments {
-			if referencesRoot(arg, root) {
-				return true
-			}
+			return expr.Function
 		}
-	case *ast.ArrayLiteral:
-		for _, element := range expr.Elements {
-			if referencesRoot(element, root) {
-				return true
-			}
+	}
+	for _, child := range ast.Children(expr) {
+		if name := rootReference(child, root); name != "" {
+			return name
 		}
 	}
-
-	return false
+	return ""
 }
 
 type evaluationScope struct {
 	object.Struct
-	env map[string]string
+	env       environment
+	extended  bool
+	ctx       Context
+	variables map[string]variable
+}
+
+// Get resolves custom variables on first use, so a resolver for a variable the
+// expression never reads is never called.
+func (s evaluationScope) Get(key string) (object.Object, bool) {
+	if obj, ok := s.Struct.Get(key); ok {
+		return obj, true
+	}
+	variable, ok := s.variables[key]
+	if !ok {
+		return nil, false
+	}
+	obj := variable.object(key, s.ctx)
+	s.Struct[key] = obj
+	return obj, true
 }
 
 func (s evaluationScope) LookupEnv(key string) (string, bool) {
-	value, ok := s.env[key]
-	return value, ok
+	return s.env.LookupEnv(key)
+}
+
+// ExtendedOperators enables the shell expansions of the Extended dialect.
+func (s evaluationScope) ExtendedOperators() bool {
+	return s.extended
 }
 
 func buildScope(ctx Context, options optionSet) evaluationScope {
-	env := mergedEnv(ctx)
+	env := newEnvironment(ctx, options)
 
 	scope := object.Struct{
 		"env":       envFunction(env),
 		"build.env": nullableEnvFunction(env),
 	}
+	if options.extended() {
+		for name, function := range extendedFunctions() {
+			scope[name] = function
+		}
+	}
 	for name, function := range options.functions {
 		scope[name] = function.objectFunction(name)
 	}
@@ -257,26 +312,33 @@ func buildScope(ctx Context, options optionSet) evaluationScope {
 		scope[key] = value
 	}
 
-	return evaluationScope{Struct: scope, env: env}
+	return evaluationScope{
+		Struct:    scope,
+		env:       env,
+		extended:  options.extended(),
+		ctx:       ctx,
+		variables: options.variables,
+	}
 }
 
-func envFunction(env map[string]string) object.Function {
+func envFunction(env environment) object.Function {
 	return func(args []object.Object) object.Object {
-		name, err := envNameArg(args)
+		name, err := envNameArg(args, env.policy)
 		if err != nil {
 			return err
 		}
-		return &object.String{Value: env[name]}
+		value, _ := env.LookupEnv(name)
+		return &object.String{Value: value}
 	}
 }
 
-func nullableEnvFunction(env map[string]string) object.Function {
+func nullableEnvFunction(env environment) object.Function {
 	return func(args []object.Object) object.Object {
-		name, err := envNameArg(args)
+		name, err := envNameArg(args, env.policy)
 		if err != nil {
 			return err
 		}
-		value, ok := env[name]
+		value, ok := env.LookupEnv(name)
 		if !ok {
 			return &object.Null{}
 		}
@@ -284,7 +346,7 @@ func nullableEnvFunction(env map[string]string) object.Function {
 	}
 }
 
-func envNameArg(args []object.Object) (string, *object.Error) {
+func envNameArg(args []object.Object, policy *EnvPolicy) (string, *object.Error) {
 	if len(args) != 1 {
 		return "", &object.Error{Message: fmt.Sprintf("wrong number of arguments for env: got %d, want 1", len(args))}
 	}
@@ -298,6 +360,9 @@ func envNameArg(args []object.Object) (string, *object.Error) {
 	if unsupportedRuntimeBuildkiteEnv(name.Value) {
 		return "", &object.Error{Message: unsupportedBuildkiteEnvMessage(name.Value)}
 	}
+	if violation := policy.violation(name.Value); violation != "" {
+		return "", &object.Error{Message: violation}
+	}
 	return name.Value, nil
 }
 
@@ -335,25 +400,6 @@ func pullRequestLabel(ctx Context) *string {
 	return ctx.Build.PullRequest.Label
 }
 
-func mergedEnv(ctx Context) map[string]string {
-	env := make(map[string]string, len(ctx.ProjectEnv)+len(ctx.BuildEnv)+len(supportedBuildkiteEnv))
-	mergeUserEnv(env, ctx.ProjectEnv)
-	mergeUserEnv(env, ctx.BuildEnv)
-	for key, value := range builtinEnv(ctx) {
-		env[key] = value
-	}
-	return env
-}
-
-func mergeUserEnv(env map[string]string, values map[string]string) {
-	for key, value := range values {
-		if unsupportedBuildkiteEnv(key) {
-			continue
-		}
-		env[key] = value
-	}
-}
-
 func builtinEnv(ctx Context) map[string]string {
 	env := map[string]string{}
 
@@ -69,6 +69,70 @@ func TestRootValidateErrorKinds(t *testing.T) {
 	runValidateCases(t, tests)
 }
 
+func TestValidateRejectsShellExpansionProblems(t *testing.T) {
+	tests := []struct {
+		expression  string
+		wantMessage string
+	}{
+		{
+			expression:  `"$BUILDKITE_AGENT_ACCESS_TOKEN" == "1"`,
+			wantMessage: `Interpolation of "BUILDKITE_AGENT_ACCESS_TOKEN" is not supported`,
+		},
+		{
+			expression:  `${DEPLOY:-$BUILDKITE_BRANC} == "main"`,
+			wantMessage: `"BUILDKITE_BRANC" is not a valid environment variable - did you mean "BUILDKITE_BRANCH"?`,
+		},
+		{
+			expression:  `"${N:x}" == "1"`,
+			wantMessage: "`${N:x}` has a non-integer substring offset \"x\"",
+		},
+		{
+			expression:  `${COMMIT:0:abc} == "1"`,
+			wantMessage: "`${COMMIT:0:abc}` has a non-integer substring offset \"abc\"",
+		},
+		{
+			expression:  `"${N:?}" == "1"`,
+			wantMessage: "`${N:?}` fails evaluation when N is unset or empty",
+		},
+		{
+			expression:  `build.branch == "release-${VERSION?}"`,
+			wantMessage: "`${VERSION?}` fails evaluation when VERSION is unset",
+		},
+		{expression: `$BUILDKITE_PULL_REQUEST_LABELS == "deploy"`},
+		{expression: `${COMMIT:0:7} == "abcdef1"`},
+		{expression: `${COMMIT:$OFFSET} == "abcdef1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			err := Validate(tt.expression, Context{})
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("Validate(%q) returned error: %v", tt.expression, err)
+				}
+				return
+			}
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("Validate(%q) error = %v, want %s", tt.expression, err, ErrorKindValidation)
+			}
+			if !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Fatalf("Validate(%q) error = %q, want it to contain %q", tt.expression, err, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestEvaluateKeepsRuntimeShellExpansionSemantics(t *testing.T) {
+	ctx := Context{BuildEnv: map[string]string{"N": "1"}}
+	got, err := Evaluate(`"${N:?}" == "1" && $BUILDKITE_AGENT_ACCESS_TOKEN == null`, ctx)
+	if err != nil {
+		t.Fatalf("Evaluate returned error: %v", err)
+	}
+	if !got {
+		t.Fatal("Evaluate = false, want true")
+	}
+}
+
 func TestParseErrorUnwrapsParserErrors(t *testing.T) {
 	err := Validate(`nope != == one`, Context{EntryPoint: EntryPointBuildCondition})
 	if err == nil {
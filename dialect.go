@@ -0,0 +1,218 @@
+package conditional
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/buildkite/conditional/internal/ast"
+	"github.com/buildkite/conditional/internal/lexer"
+	"github.com/buildkite/conditional/internal/object"
+	"github.com/buildkite/conditional/internal/parser"
+	"github.com/buildkite/conditional/internal/regex"
+	"github.com/buildkite/conditional/internal/shell"
+)
+
+// Dialect selects the conditional language accepted by validation and
+// evaluation.
+type Dialect string
+
+const (
+	// BuildkiteServer accepts exactly the language evaluated by the Buildkite
+	// server. It is the default dialect.
+	BuildkiteServer Dialect = "buildkite_server"
+	// Extended accepts the Buildkite language plus extensions for internal
+	// tooling: number ordering operators, the in operator, the intersects,
+	// all_of and match functions, the m and x regexp flags, and the length,
+	// pattern removal, replacement and case conversion shell expansions such
+	// as ${#NAME}, ${NAME##*/}, ${NAME/from/to} and ${NAME^^}. Expressions
+	// using extensions are rejected by Buildkite, so do not use this dialect
+	// to validate conditionals that will be uploaded.
+	Extended Dialect = "extended"
+)
+
+// WithDialect selects the conditional language. Without this option, or with
+// BuildkiteServer, expressions using extended constructs fail validation with
+// a "not supported by Buildkite" error.
+func WithDialect(dialect Dialect) Option {
+	return func(options *optionSet) error {
+		switch dialect {
+		case BuildkiteServer, Extended:
+			options.dialect = dialect
+			return nil
+		default:
+			return validationError("unknown dialect %q", dialect)
+		}
+	}
+}
+
+// extendedOperators are infix operators that only the Extended dialect lexes.
+var extendedOperators = map[string]struct{}{
+	"<":  {},
+	"<=": {},
+	">":  {},
+	">=": {},
+	"in": {},
+}
+
+// extendedFunctionTypes declares the functions enabled by the Extended
+// dialect.
+var extendedFunctionTypes = map[string]functionSignature{
+	"intersects": {
+		args:     []valueType{stringArrayType(), stringArrayType()},
+		ret:      boolType(),
+		nullable: true,
+	},
+	"all_of": {
+		args:     []valueType{stringArrayType(), stringArrayType()},
+		ret:      boolType(),
+		nullable: true,
+	},
+	"match": {
+		args:     []valueType{stringType(), {kind: kindRegexp}},
+		ret:      stringArrayType(),
+		nullable: true,
+	},
+}
+
+func extendedFunctions() map[string]object.Function {
+	return map[string]object.Function{
+		"intersects": arrayPredicateFunction("intersects", intersects),
+		"all_of":     arrayPredicateFunction("all_of", allOf),
+		"match":      matchFunction,
+	}
+}
+
+func (o optionSet) extended() bool {
+	return o.dialect == Extended
+}
+
+func (o optionSet) lexerOptions() []lexer.Option {
+	if !o.extended() {
+		return nil
+	}
+	return extendedLexerOptions()
+}
+
+func extendedLexerOptions() []lexer.Option {
+	return []lexer.Option{
+		lexer.WithOrderingOperators(),
+		lexer.WithMembershipOperator(),
+	}
+}
+
+func extendedFunction(name string) bool {
+	_, ok := extendedFunctionTypes[name]
+	return ok
+}
+
+// unsupportedSyntaxError explains a BuildkiteServer parse failure when the
+// expression would parse under the Extended dialect. It returns nil when the
+// expression is invalid in both dialects.
+func unsupportedSyntaxError(expression string, options optionSet) error {
+	p := parser.New(lexer.New(expression, extendedLexerOptions()...), options.parserOptions()...)
+	expr := p.Parse()
+	if len(p.Errors()) > 0 || expr == nil {
+		return nil
+	}
+	construct, ok := options.extendedConstruct(expr)
+	if !ok {
+		return nil
+	}
+	return notSupportedByBuildkite(construct)
+}
+
+// extendedFlagError explains a BuildkiteServer parse failure caused by a
+// regexp flag that only the Extended dialect accepts. It remains a parse
+// error, as the server rejects the flag while parsing.
+func extendedFlagError(errs []error) error {
+	for _, err := range errs {
+		var flagErr *regex.FlagError
+		if errors.As(err, &flagErr) && flagErr.Extended {
+			return &Error{
+				Kind:    ErrorKindParse,
+				Message: fmt.Sprintf("the `%c` regexp flag is not supported by Buildkite; it requires the %s dialect", flagErr.Flag, Extended),
+				Cause:   err,
+			}
+		}
+	}
+	return nil
+}
+
+// extendedConstruct describes the first construct in expr that only the
+// Extended dialect accepts. Caller-owned functions are never extensions, even
+// when they share a name with an extended function.
+func (o optionSet) extendedConstruct(expr ast.Expression) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.PrefixExpression:
+		return o.extendedConstruct(expr.Right)
+	case *ast.ConditionalExpression:
+		for _, child := range []ast.Expression{expr.Condition, expr.Consequence, expr.Alternative} {
+			if construct, ok := o.extendedConstruct(child); ok {
+				return construct, true
+			}
+		}
+	case *ast.InfixExpression:
+		if _, ok := extendedOperators[expr.Operator]; ok {
+			return fmt.Sprintf("the `%s` operator", expr.Operator), true
+		}
+		if construct, ok := o.extendedConstruct(expr.Left); ok {
+			return construct, true
+		}
+		return o.extendedConstruct(expr.Right)
+	case *ast.CallExpression:
+		if extendedFunction(expr.Function) && !o.registered(expr.Function) {
+			return fmt.Sprintf("the `%s` function", expr.Function), true
+		}
+		for _, arg := range expr.Arguments {
+			if construct, ok := o.extendedConstruct(arg); ok {
+				return construct, true
+			}
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range expr.Elements {
+			if construct, ok := o.extendedConstruct(element); ok {
+				return construct, true
+			}
+		}
+	case *ast.Regexp:
+		for _, flag := range expr.Flags {
+			if flag == 'm' || flag == 'x' {
+				return fmt.Sprintf("the `%c` regexp flag", flag), true
+			}
+		}
+	case *ast.ShellExpansion:
+		return extendedShellExpansion(expr.Raw)
+	case *ast.StringLiteral:
+		if runtimeStringLiteral(expr) {
+			return extendedShellExpansion(expr.Token.Raw)
+		}
+	}
+
+	return "", false
+}
+
+func extendedShellExpansion(raw string) (string, bool) {
+	for _, expansion := range shell.Expansions(raw) {
+		if expansion.Extended() {
+			return fmt.Sprintf("the `%s` shell expansion", expansion.Raw), true
+		}
+	}
+	return "", false
+}
+
+// validateDialect rejects extended constructs under the BuildkiteServer
+// dialect. Parsing already refuses extended syntax, so this guards against
+// extensions reaching server-bound validation by any other path.
+func validateDialect(expr ast.Expression, options optionSet) error {
+	if options.extended() {
+		return nil
+	}
+	if construct, ok := options.extendedConstruct(expr); ok {
+		return notSupportedByBuildkite(construct)
+	}
+	return nil
+}
+
+func notSupportedByBuildkite(construct string) *Error {
+	return validationError("%s is not supported by Buildkite; it requires the %s dialect", construct, Extended)
+}
@@ -0,0 +1,388 @@
+package conditional
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildkiteServerDialectRejectsExtendedConstructs(t *testing.T) {
+	tests := []struct {
+		expression          string
+		wantMessageContains string
+	}{
+		{expression: `build.number > 100`, wantMessageContains: "the `>` operator is not supported by Buildkite"},
+		{expression: `build.number <= 100 || build.branch == "main"`, wantMessageContains: "the `<=` operator is not supported by Buildkite"},
+		{expression: `build.branch in ["main"]`, wantMessageContains: "the `in` operator is not supported by Buildkite"},
+		{expression: `intersects(build.creator.teams, ["deploy"])`, wantMessageContains: "the `intersects` function is not supported by Buildkite"},
+		{expression: `build.branch == "main" && all_of(build.creator.teams, ["deploy"])`, wantMessageContains: "the `all_of` function is not supported by Buildkite"},
+		{expression: `match(build.branch, /^release\/(\d+)/) includes "12"`, wantMessageContains: "the `match` function is not supported by Buildkite"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			for _, opts := range [][]Option{nil, {WithDialect(BuildkiteServer)}} {
+				err := Validate(tt.expression, Context{}, opts...)
+				if !IsErrorKind(err, ErrorKindValidation) {
+					t.Fatalf("Validate(%q) error = %v, want %s", tt.expression, err, ErrorKindValidation)
+				}
+				if !strings.Contains(err.Error(), tt.wantMessageContains) {
+					t.Fatalf("Validate(%q) error = %v, want message containing %q", tt.expression, err, tt.wantMessageContains)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildkiteServerDialectKeepsParseErrors(t *testing.T) {
+	err := Validate(`build.number > `, Context{})
+	if !IsErrorKind(err, ErrorKindParse) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindParse)
+	}
+}
+
+func TestBuildkiteServerDialectRejectsExtendedRegexpFlags(t *testing.T) {
+	for _, expression := range []string{`build.message =~ /^Fixes/m`, `build.branch =~ /^main $/x`, `build.branch =~ /main/im`} {
+		t.Run(expression, func(t *testing.T) {
+			err := Validate(expression, Context{})
+			if !IsErrorKind(err, ErrorKindParse) {
+				t.Fatalf("Validate(%q) error = %v, want %s", expression, err, ErrorKindParse)
+			}
+			if !strings.Contains(err.Error(), "regexp flag is not supported by Buildkite; it requires the extended dialect") {
+				t.Fatalf("Validate(%q) error = %v, want a not supported by Buildkite message", expression, err)
+			}
+		})
+	}
+}
+
+func TestBuildkiteServerDialectRejectsExtendedShellExpansions(t *testing.T) {
+	tests := []struct {
+		expression string
+		construct  string
+	}{
+		{expression: `${#DEPLOY_TARGET} == "10"`, construct: "${#DEPLOY_TARGET}"},
+		{expression: `${DEPLOY_TARGET##*/} == "prod"`, construct: "${DEPLOY_TARGET##*/}"},
+		{expression: `"deploy-${DEPLOY_TARGET/-/_}" == "deploy-prod"`, construct: "${DEPLOY_TARGET/-/_}"},
+		{expression: `build.branch == "${DEPLOY_BRANCH:-${DEPLOY_TARGET^^}}"`, construct: "${DEPLOY_TARGET^^}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			err := Validate(tt.expression, Context{})
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("Validate(%q) error = %v, want %s", tt.expression, err, ErrorKindValidation)
+			}
+			want := "the `" + tt.construct + "` shell expansion is not supported by Buildkite; it requires the extended dialect"
+			if !strings.Contains(err.Error(), want) {
+				t.Fatalf("Validate(%q) error = %v, want %q", tt.expression, err, want)
+			}
+			if err := Validate(tt.expression, Context{}, WithDialect(Extended)); err != nil {
+				t.Fatalf("Validate(%q) with the extended dialect returned error: %v", tt.expression, err)
+			}
+		})
+	}
+}
+
+func TestBuildkiteServerDialectAllowsCustomFunctionWithExtendedName(t *testing.T) {
+	intersects := WithFunction("intersects", Function{
+		Args:   []ValueType{StringArrayType, StringArrayType},
+		Return: BoolType,
+		Eval: func(args []Value) (Value, error) {
+			return BoolValue(true), nil
+		},
+	})
+
+	if err := Validate(`intersects(build.creator.teams, ["deploy"])`, Context{}, intersects); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+}
+
+func TestBuildkiteServerDialectAllowsInAsAName(t *testing.T) {
+	in := WithVariable("in", StringType, func(Context) Value { return StringValue("x") })
+
+	got, err := Evaluate(`in == "x"`, Context{}, in)
+	if err != nil {
+		t.Fatalf("Evaluate returned error: %v", err)
+	}
+	if !got {
+		t.Fatal("Evaluate = false, want true")
+	}
+}
+
+func TestEvaluatorDialect(t *testing.T) {
+	var zero Evaluator
+	if got := zero.Dialect(); got != BuildkiteServer {
+		t.Fatalf("zero Evaluator.Dialect() = %q, want %q", got, BuildkiteServer)
+	}
+
+	evaluator, err := NewEvaluator(WithDialect(Extended))
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+	if got := evaluator.Dialect(); got != Extended {
+		t.Fatalf("Evaluator.Dialect() = %q, want %q", got, Extended)
+	}
+}
+
+func TestWithDialectRejectsUnknownDialect(t *testing.T) {
+	_, err := NewEvaluator(WithDialect("ruby"))
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+	}
+}
+
+func TestExtendedDialectNumberOrdering(t *testing.T) {
+	ctx := Context{Build: Build{Number: intptr(120)}}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `build.number > 100`, want: true},
+		{expression: `build.number >= 120`, want: true},
+		{expression: `build.number < 100`, want: false},
+		{expression: `build.number <= 119`, want: false},
+		{expression: `build.number > 100 && build.branch == null`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := Evaluate(tt.expression, ctx, WithDialect(Extended))
+			if err != nil {
+				t.Fatalf("Evaluate(%q) returned error: %v", tt.expression, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Evaluate(%q) = %t, want %t", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendedDialectRegexpFlags(t *testing.T) {
+	message := "Release notes\n\nFixes #123\nDeploy: yes"
+	ctx := Context{Build: Build{Message: &message}}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `build.message =~ /^Fixes #\d+$/m`, want: true},
+		{expression: `build.message =~ /notes..Fixes/m`, want: true},
+		{expression: `build.message =~ /^fixes #\d+$/im`, want: true},
+		{expression: `build.message =~ /^ Deploy: \s yes $/xm`, want: true},
+		{expression: `build.message =~ /Fixes \ \# \d+  # issue reference/x`, want: true},
+		{expression: `build.message =~ /^Fixes/`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := Evaluate(tt.expression, ctx, WithDialect(Extended))
+			if err != nil {
+				t.Fatalf("Evaluate(%q) returned error: %v", tt.expression, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Evaluate(%q) = %t, want %t", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendedDialectShellExpansions(t *testing.T) {
+	branch := "release/v1.2"
+	ctx := Context{
+		Build:    Build{Branch: &branch},
+		BuildEnv: map[string]string{"DEPLOY_TARGET": "us-east/prod", "SERVICE": "api-gateway"},
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `${DEPLOY_TARGET##*/} == "prod"`, want: true},
+		{expression: `${DEPLOY_TARGET%/*} == "us-east"`, want: true},
+		{expression: `${#SERVICE} == "11"`, want: true},
+		{expression: `"${SERVICE//-/_}" == "api_gateway"`, want: true},
+		{expression: `${SERVICE^^} == "API-GATEWAY"`, want: true},
+		{expression: `build.branch == "release/${SERVICE%-*}"`, want: false},
+		{expression: `${MISSING##*/} == null`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := Evaluate(tt.expression, ctx, WithDialect(Extended))
+			if err != nil {
+				t.Fatalf("Evaluate(%q) returned error: %v", tt.expression, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Evaluate(%q) = %t, want %t", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendedDialectNumberOrderingTypeValidation(t *testing.T) {
+	for _, expression := range []string{
+		`build.branch > "main"`,
+		`build.number > "1"`,
+		`null < 1`,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			err := Validate(expression, Context{}, WithDialect(Extended))
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("Validate(%q) error = %v, want %s", expression, err, ErrorKindValidation)
+			}
+		})
+	}
+}
+
+func TestExtendedDialectNumberOrderingNullFailsClosed(t *testing.T) {
+	_, err := Evaluate(`build.number > 100`, Context{}, WithDialect(Extended))
+	if !IsErrorKind(err, ErrorKindEvaluation) {
+		t.Fatalf("Evaluate error = %v, want %s", err, ErrorKindEvaluation)
+	}
+}
+
+func TestExtendedDialectMembership(t *testing.T) {
+	ctx := Context{
+		Build: Build{
+			Branch: str("release"),
+			State:  str("passed"),
+			PullRequest: PullRequest{
+				Labels: []string{"deploy", "backend"},
+			},
+		},
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `build.branch in ["main", "release"]`, want: true},
+		{expression: `build.branch in ["main"]`, want: false},
+		{expression: `build.state in ["passed", "failed"]`, want: true},
+		{expression: `build.tag in ["v1"]`, want: false},
+		{expression: `build.branch in build.pull_request.labels`, want: false},
+		{expression: `intersects(build.pull_request.labels, ["deploy", "release"])`, want: true},
+		{expression: `intersects(build.pull_request.labels, ["release"])`, want: false},
+		{expression: `intersects(build.creator.teams, ["deploy"])`, want: false},
+		{expression: `all_of(build.pull_request.labels, ["deploy", "backend"])`, want: true},
+		{expression: `all_of(build.pull_request.labels, ["deploy", "release"])`, want: false},
+		{expression: `all_of(build.pull_request.labels, [])`, want: true},
+		{expression: `intersects(["deploy"], null)`, want: false},
+		{expression: `all_of(null, ["deploy"])`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := Evaluate(tt.expression, ctx, WithDialect(Extended))
+			if err != nil {
+				t.Fatalf("Evaluate(%q) returned error: %v", tt.expression, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Evaluate(%q) = %t, want %t", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendedDialectMatch(t *testing.T) {
+	tests := []struct {
+		expression string
+		branch     string
+		want       bool
+	}{
+		{expression: `match(build.branch, /^release\/(\d+)/) includes "12"`, branch: "release/12", want: true},
+		{expression: `match(build.branch, /^release\/(\d+)/) includes "12"`, branch: "release/13", want: false},
+		{expression: `match(build.branch, /^RELEASE\/(\d+)/i) includes "12"`, branch: "release/12", want: true},
+		{expression: `match(build.branch, /^release\/(\d+)/) == null`, branch: "main", want: true},
+		{expression: `match(build.branch, /^release\//) == []`, branch: "release/12", want: true},
+		{expression: `match(build.branch, /^(main)|(master)$/) == ["", "master"]`, branch: "master", want: true},
+		{expression: `match(build.tag, /^v(\d+)/) == null`, branch: "main", want: true},
+		{expression: `match(null, /a/) == null`, branch: "main", want: true},
+		{
+			expression: `(match(build.branch, /^release\/(\d+)/) includes "12" ? "deploy" : "skip") == "deploy"`,
+			branch:     "release/12",
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression+" on "+tt.branch, func(t *testing.T) {
+			ctx := Context{Build: Build{Branch: str(tt.branch)}}
+			got, err := Evaluate(tt.expression, ctx, WithDialect(Extended))
+			if err != nil {
+				t.Fatalf("Evaluate(%q) returned error: %v", tt.expression, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Evaluate(%q) = %t, want %t", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendedDialectMatchTypeValidation(t *testing.T) {
+	for _, expression := range []string{
+		`match(build.branch, "release") == null`,
+		`match(build.number, /1/) == null`,
+		`match(build.branch) == null`,
+		`match(build.branch, null) == null`,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			err := Validate(expression, Context{}, WithDialect(Extended))
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("Validate(%q) error = %v, want %s", expression, err, ErrorKindValidation)
+			}
+		})
+	}
+}
+
+func TestExtendedDialectMembershipTypeValidation(t *testing.T) {
+	tests := []struct {
+		expression          string
+		wantMessageContains string
+	}{
+		{expression: `build.state in ["passed", "pased"]`, wantMessageContains: "\"pased\" is not a valid `build.state`"},
+		{expression: `build.number in ["1"]`, wantMessageContains: "expected string or null but found number"},
+		{expression: `build.branch in "main"`, wantMessageContains: "expected string array or null but found string"},
+		{expression: `intersects(build.branch, ["main"])`, wantMessageContains: "expected string array but found string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			err := Validate(tt.expression, Context{}, WithDialect(Extended))
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("Validate(%q) error = %v, want %s", tt.expression, err, ErrorKindValidation)
+			}
+			if !strings.Contains(err.Error(), tt.wantMessageContains) {
+				t.Fatalf("Validate(%q) error = %v, want message containing %q", tt.expression, err, tt.wantMessageContains)
+			}
+		})
+	}
+}
+
+func TestExtendedDialectReservesFunctionNames(t *testing.T) {
+	_, err := NewEvaluator(
+		WithFunction("intersects", Function{
+			Return: BoolType,
+			Eval: func(args []Value) (Value, error) {
+				return BoolValue(true), nil
+			},
+		}),
+		WithDialect(Extended),
+	)
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+	}
+}
+
+func TestExtendedDialectReservesIn(t *testing.T) {
+	in := WithVariable("in", StringType, func(Context) Value { return StringValue("x") })
+
+	for _, opts := range [][]Option{
+		{in, WithDialect(Extended)},
+		{WithDialect(Extended), in},
+	} {
+		_, err := NewEvaluator(opts...)
+		if !IsErrorKind(err, ErrorKindValidation) {
+			t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+		}
+		if !strings.Contains(err.Error(), "`in` is an operator in the extended dialect") {
+			t.Fatalf("NewEvaluator error = %v, want the in operator message", err)
+		}
+	}
+}
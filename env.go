@@ -2,55 +2,89 @@ package conditional
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 )
 
-var supportedBuildkiteEnvNames = []string{
-	"BUILDKITE_BRANCH",
-	"BUILDKITE_TAG",
-	"BUILDKITE_MESSAGE",
-	"BUILDKITE_COMMIT",
-	"BUILDKITE_PIPELINE_SLUG",
-	"BUILDKITE_PIPELINE_NAME",
-	"BUILDKITE_PIPELINE_ID",
-	"BUILDKITE_ORGANIZATION_SLUG",
-	"BUILDKITE_TRIGGERED_FROM_BUILD_ID",
-	"BUILDKITE_TRIGGERED_FROM_BUILD_NUMBER",
-	"BUILDKITE_TRIGGERED_FROM_BUILD_PIPELINE_SLUG",
-	"BUILDKITE_TRIGGERED_FROM_BUILD_JOB_ID",
-	"BUILDKITE_REBUILT_FROM_BUILD_ID",
-	"BUILDKITE_REBUILT_FROM_BUILD_NUMBER",
-	"BUILDKITE_REPO",
-	"BUILDKITE_PULL_REQUEST",
-	"BUILDKITE_PULL_REQUEST_BASE_BRANCH",
-	"BUILDKITE_PULL_REQUEST_REPO",
-	"BUILDKITE_PULL_REQUEST_USING_MERGE_REFSPEC",
-	"BUILDKITE_MERGE_QUEUE_BASE_BRANCH",
-	"BUILDKITE_MERGE_QUEUE_BASE_COMMIT",
-	"BUILDKITE_GIT_DIFF_BASE",
-	"BUILDKITE_GITHUB_ACTION",
-	"BUILDKITE_GITHUB_COMMENT_ID",
-	"BUILDKITE_GITHUB_DEPLOYMENT_ID",
-	"BUILDKITE_GITHUB_DEPLOYMENT_TASK",
-	"BUILDKITE_GITHUB_DEPLOYMENT_ENVIRONMENT",
-	"BUILDKITE_GITHUB_DEPLOYMENT_PAYLOAD",
-	"BUILDKITE_GITHUB_EVENT",
-	"BUILDKITE_GITHUB_REVIEW_ID",
-	"BUILDKITE_GITHUB_CHECK_RUN_CONCLUSION",
-	"BUILDKITE_GITHUB_CHECK_RUN_NAME",
-	"BUILDKITE_GITHUB_DEPLOYMENT_STATUS_ENVIRONMENT",
-	"BUILDKITE_GITHUB_DEPLOYMENT_STATUS_STATE",
-	"BUILDKITE_GITHUB_RELEASE_DRAFT",
-	"BUILDKITE_GITHUB_RELEASE_PRERELEASE",
-	"BUILDKITE_GITHUB_RELEASE_TAG",
-	"BUILDKITE_GITHUB_REVIEW_STATE",
+// EnvVarInfo describes a BUILDKITE_* environment variable conditionals may
+// read.
+type EnvVarInfo struct {
+	Name        string
+	Description string
+	// Field names the Context field that produces the value, such as
+	// "Build.Branch". It is empty for values read from Context.BuildEnv.
+	Field string
+	// RuntimeOnly reports whether literal env() and build.env() arguments
+	// naming the variable fail validation, as they do on Buildkite. Shell
+	// expansions and names built at runtime can still read it.
+	RuntimeOnly bool
 }
 
+var buildkiteEnvCatalogue = []EnvVarInfo{
+	{Name: "BUILDKITE_BRANCH", Description: "The branch the build is for.", Field: "Build.Branch"},
+	{Name: "BUILDKITE_TAG", Description: "The tag the build is for, or blank.", Field: "Build.Tag"},
+	{Name: "BUILDKITE_MESSAGE", Description: "The message of the build, usually the commit message, or blank.", Field: "Build.Message"},
+	{Name: "BUILDKITE_COMMIT", Description: "The commit the build is for.", Field: "Build.Commit"},
+	{Name: "BUILDKITE_PIPELINE_SLUG", Description: "The slug of the pipeline.", Field: "Pipeline.Slug"},
+	{Name: "BUILDKITE_PIPELINE_NAME", Description: "The name of the pipeline.", Field: "Pipeline.Name"},
+	{Name: "BUILDKITE_PIPELINE_ID", Description: "The UUID of the pipeline.", Field: "Pipeline.ID"},
+	{Name: "BUILDKITE_ORGANIZATION_SLUG", Description: "The slug of the organization.", Field: "Organization.Slug"},
+	{Name: "BUILDKITE_TRIGGERED_FROM_BUILD_ID", Description: "The UUID of the build that triggered this build, or blank.", Field: "Build.TriggeredFrom.BuildID"},
+	{Name: "BUILDKITE_TRIGGERED_FROM_BUILD_NUMBER", Description: "The number of the build that triggered this build, or blank.", Field: "Build.TriggeredFrom.BuildNumber"},
+	{Name: "BUILDKITE_TRIGGERED_FROM_BUILD_PIPELINE_SLUG", Description: "The pipeline slug of the build that triggered this build, or blank.", Field: "Build.TriggeredFrom.PipelineSlug"},
+	{Name: "BUILDKITE_TRIGGERED_FROM_BUILD_JOB_ID", Description: "The UUID of the job that triggered this build, or blank.", Field: "Build.TriggeredFrom.JobID"},
+	{Name: "BUILDKITE_REBUILT_FROM_BUILD_ID", Description: "The UUID of the build this build is a rebuild of, or blank.", Field: "Build.RebuiltFrom.BuildID"},
+	{Name: "BUILDKITE_REBUILT_FROM_BUILD_NUMBER", Description: "The number of the build this build is a rebuild of, or blank.", Field: "Build.RebuiltFrom.BuildNumber"},
+	{Name: "BUILDKITE_REPO", Description: "The repository URL of the pipeline.", Field: "Pipeline.Repository"},
+	{Name: "BUILDKITE_PULL_REQUEST", Description: "The number of the pull request, or \"false\" for builds that are not pull requests.", Field: "Build.PullRequest.ID"},
+	{Name: "BUILDKITE_PULL_REQUEST_BASE_BRANCH", Description: "The branch the pull request targets, or blank.", Field: "Build.PullRequest.BaseBranch"},
+	{Name: "BUILDKITE_PULL_REQUEST_REPO", Description: "The repository URL of the pull request, or blank.", Field: "Build.PullRequest.Repository"},
+	{Name: "BUILDKITE_PULL_REQUEST_USING_MERGE_REFSPEC", Description: "\"true\" when the build checks out the pull request's merge refspec, otherwise blank.", Field: "Build.PullRequest.UsingMergeRefspec"},
+	{Name: "BUILDKITE_MERGE_QUEUE_BASE_BRANCH", Description: "The branch the merge queue targets, or blank.", Field: "Build.MergeQueue.BaseBranch"},
+	{Name: "BUILDKITE_MERGE_QUEUE_BASE_COMMIT", Description: "The commit the merge queue is based on, or blank.", Field: "Build.MergeQueue.BaseCommit"},
+	{Name: "BUILDKITE_GIT_DIFF_BASE", Description: "The merge queue base commit when the pipeline diffs against it, otherwise the merge queue base branch, or blank outside a merge queue.", Field: "Build.MergeQueue"},
+	{Name: "BUILDKITE_GITHUB_ACTION", Description: "The action of the GitHub webhook event that created the build."},
+	{Name: "BUILDKITE_GITHUB_COMMENT_ID", Description: "The ID of the GitHub comment that created the build."},
+	{Name: "BUILDKITE_GITHUB_DEPLOYMENT_ID", Description: "The ID of the GitHub deployment."},
+	{Name: "BUILDKITE_GITHUB_DEPLOYMENT_TASK", Description: "The task of the GitHub deployment."},
+	{Name: "BUILDKITE_GITHUB_DEPLOYMENT_ENVIRONMENT", Description: "The environment of the GitHub deployment."},
+	{Name: "BUILDKITE_GITHUB_DEPLOYMENT_PAYLOAD", Description: "The JSON payload of the GitHub deployment."},
+	{Name: "BUILDKITE_GITHUB_EVENT", Description: "The GitHub webhook event that created the build."},
+	{Name: "BUILDKITE_GITHUB_REVIEW_ID", Description: "The ID of the GitHub pull request review."},
+	{Name: "BUILDKITE_GITHUB_CHECK_RUN_CONCLUSION", Description: "The conclusion of the GitHub check run."},
+	{Name: "BUILDKITE_GITHUB_CHECK_RUN_NAME", Description: "The name of the GitHub check run."},
+	{Name: "BUILDKITE_GITHUB_DEPLOYMENT_STATUS_ENVIRONMENT", Description: "The environment of the GitHub deployment status."},
+	{Name: "BUILDKITE_GITHUB_DEPLOYMENT_STATUS_STATE", Description: "The state of the GitHub deployment status."},
+	{Name: "BUILDKITE_GITHUB_RELEASE_DRAFT", Description: "Whether the GitHub release is a draft."},
+	{Name: "BUILDKITE_GITHUB_RELEASE_PRERELEASE", Description: "Whether the GitHub release is a prerelease."},
+	{Name: "BUILDKITE_GITHUB_RELEASE_TAG", Description: "The tag of the GitHub release."},
+	{Name: "BUILDKITE_GITHUB_REVIEW_STATE", Description: "The state of the GitHub pull request review."},
+	{Name: "BUILDKITE_PULL_REQUEST_LABELS", Description: "The labels on the pull request, separated by commas.", Field: "Build.PullRequest.Labels", RuntimeOnly: true},
+}
+
+// BuildkiteEnv describes the BUILDKITE_* environment variables conditionals
+// may read, in catalogue order. Other BUILDKITE_* names are rejected.
+func BuildkiteEnv() []EnvVarInfo {
+	return slices.Clone(buildkiteEnvCatalogue)
+}
+
+var supportedBuildkiteEnvNames = func() []string {
+	names := make([]string, 0, len(buildkiteEnvCatalogue))
+	for _, info := range buildkiteEnvCatalogue {
+		if !info.RuntimeOnly {
+			names = append(names, info.Name)
+		}
+	}
+	return names
+}()
+
 var supportedBuildkiteEnv = stringSet(supportedBuildkiteEnvNames)
 var runtimeSupportedBuildkiteEnv = func() map[string]struct{} {
-	values := stringSet(supportedBuildkiteEnvNames)
-	values["BUILDKITE_PULL_REQUEST_LABELS"] = struct{}{}
+	values := make(map[string]struct{}, len(buildkiteEnvCatalogue))
+	for _, info := range buildkiteEnvCatalogue {
+		values[info.Name] = struct{}{}
+	}
 	return values
 }()
 
@@ -109,6 +143,13 @@ func unsupportedBuildkiteEnvMessage(key string) string {
 }
 
 func suggestBuildkiteEnv(input string) string {
+	return suggestName(input, supportedBuildkiteEnvNames)
+}
+
+// suggestName returns the candidate closest to input, other than input
+// itself, or "" when no candidate is close enough. Ties go to the earlier
+// candidate.
+func suggestName(input string, names []string) string {
 	if input == "" {
 		return ""
 	}
@@ -123,7 +164,7 @@ func suggestBuildkiteEnv(input string) string {
 		score float64
 	}
 	candidates := []candidate{}
-	for _, word := range supportedBuildkiteEnvNames {
+	for _, word := range names {
 		if input == word {
 			continue
 		}
@@ -2,6 +2,7 @@ package conditional
 
 import (
 	"fmt"
+	"maps"
 	"slices"
 	"testing"
 )
@@ -120,3 +121,36 @@ func TestDocumentedGitHubBuildkiteEnvValuesComeFromBuildEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildkiteEnvCatalogueFieldsMatchBuiltinEnv(t *testing.T) {
+	ctx := Context{
+		Build:        Build{Branch: str("main"), Commit: str("abc123")},
+		Pipeline:     Pipeline{Repository: str("git@github.com:acme/repo.git"), Slug: str("deploy"), Name: str("Deploy"), ID: str("018f")},
+		Organization: Organization{Slug: str("acme")},
+	}
+	builtin := builtinEnv(ctx)
+
+	var fields []string
+	for _, info := range BuildkiteEnv() {
+		if info.Description == "" {
+			t.Fatalf("BuildkiteEnv entry %s has no description", info.Name)
+		}
+		if info.Field == "" {
+			if _, ok := builtin[info.Name]; ok {
+				t.Fatalf("BuildkiteEnv entry %s has no field, but Context produces it", info.Name)
+			}
+			continue
+		}
+		fields = append(fields, info.Name)
+	}
+	if want := slices.Sorted(maps.Keys(builtin)); !slices.Equal(slices.Sorted(slices.Values(fields)), want) {
+		t.Fatalf("BuildkiteEnv names with fields = %v, want %v", fields, want)
+	}
+}
+
+func TestBuildkiteEnvReturnsACopy(t *testing.T) {
+	BuildkiteEnv()[0].Name = "CHANGED"
+	if BuildkiteEnv()[0].Name != "BUILDKITE_BRANCH" {
+		t.Fatal("BuildkiteEnv returned the shared catalogue")
+	}
+}
@@ -0,0 +1,114 @@
+package conditional
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/buildkite/conditional/internal/ast"
+	"github.com/buildkite/conditional/internal/shell"
+)
+
+// EnvPolicy restricts the environment variables conditionals may read through
+// env(), build.env() and shell expansions. Patterns use path.Match syntax,
+// such as CI_FLAG_* or *_TOKEN.
+type EnvPolicy struct {
+	// Allow restricts custom environment variables to names matching one of
+	// these patterns. Supported BUILDKITE_* variables are always allowed unless
+	// denied. An empty Allow allows every name.
+	Allow []string
+	// Deny forbids names matching any of these patterns, including BUILDKITE_*
+	// names. Deny takes precedence over Allow.
+	Deny []string
+}
+
+// WithEnvPolicy enforces policy on environment reads. Literal names passed to
+// env() or build.env() and names in shell expansions are checked during
+// validation; names only known at runtime fail evaluation. A later
+// WithEnvPolicy replaces an earlier one.
+func WithEnvPolicy(policy EnvPolicy) Option {
+	return func(options *optionSet) error {
+		for _, patterns := range [][]string{policy.Allow, policy.Deny} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return validationError("invalid environment policy pattern %q: %v", pattern, err)
+				}
+			}
+		}
+		policy.Allow = append([]string(nil), policy.Allow...)
+		policy.Deny = append([]string(nil), policy.Deny...)
+		options.envPolicy = &policy
+		return nil
+	}
+}
+
+// violation describes why policy forbids reading name, or returns "" when
+// the read is allowed. A nil policy allows every read.
+func (p *EnvPolicy) violation(name string) string {
+	if p == nil {
+		return ""
+	}
+	if matchesAny(p.Deny, name) {
+		return fmt.Sprintf("reading %q is denied by the environment policy", name)
+	}
+	if _, builtin := runtimeSupportedBuildkiteEnv[name]; builtin || len(p.Allow) == 0 {
+		return ""
+	}
+	if !matchesAny(p.Allow, name) {
+		return fmt.Sprintf("reading %q is not allowed by the environment policy", name)
+	}
+	return ""
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validateEnvPolicy rejects the first literal env() or build.env() argument
+// or shell expansion name that the environment policy forbids.
+func validateEnvPolicy(expr ast.Expression, options optionSet) error {
+	if options.envPolicy == nil {
+		return nil
+	}
+	for _, name := range envReferences(expr) {
+		if violation := options.envPolicy.violation(name); violation != "" {
+			return validationError("%s", violation)
+		}
+	}
+	return nil
+}
+
+// envReferences returns the environment variable names expr reads through
+// literal env() and build.env() arguments and shell expansions, in source
+// order.
+func envReferences(expr ast.Expression) []string {
+	switch expr := expr.(type) {
+	case *ast.ShellExpansion:
+		return shell.References(expr.Raw)
+	case *ast.StringLiteral:
+		if runtimeStringLiteral(expr) {
+			return shell.References(expr.Token.Raw)
+		}
+		return nil
+	case *ast.CallExpression:
+		var names []string
+		if (expr.Function == "env" || expr.Function == "build.env") && len(expr.Arguments) == 1 {
+			if literal, ok := staticStringLiteral(expr.Arguments[0]); ok {
+				names = append(names, literal.Value)
+			}
+		}
+		for _, arg := range expr.Arguments {
+			names = append(names, envReferences(arg)...)
+		}
+		return names
+	}
+	var names []string
+	for _, child := range ast.Children(expr) {
+		names = append(names, envReferences(child)...)
+	}
+	return names
+}
@@ -0,0 +1,95 @@
+package conditional
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvPolicyValidation(t *testing.T) {
+	policy := WithEnvPolicy(EnvPolicy{
+		Allow: []string{"CI_FLAG_*"},
+		Deny:  []string{"*_TOKEN", "BUILDKITE_GITHUB_DEPLOYMENT_PAYLOAD"},
+	})
+	tests := []struct {
+		expression  string
+		wantMessage string
+	}{
+		{expression: `env("CI_FLAG_DEPLOY") == "true"`},
+		{expression: `build.env("CI_FLAG_DEPLOY") == null`},
+		{expression: `${CI_FLAG_DEPLOY:-false} == "true"`},
+		{expression: `env("BUILDKITE_BRANCH") == "main"`},
+		{
+			expression:  `env("ROUTING_TABLE") == "blue"`,
+			wantMessage: `reading "ROUTING_TABLE" is not allowed by the environment policy`,
+		},
+		{
+			expression:  `build.env("CI_FLAG_TOKEN") != null`,
+			wantMessage: `reading "CI_FLAG_TOKEN" is denied by the environment policy`,
+		},
+		{
+			expression:  `$DEPLOY_TOKEN == "x"`,
+			wantMessage: `reading "DEPLOY_TOKEN" is denied by the environment policy`,
+		},
+		{
+			expression:  `"${CI_FLAG_DEPLOY:-$ROUTING_TABLE}" == "x"`,
+			wantMessage: `reading "ROUTING_TABLE" is not allowed by the environment policy`,
+		},
+		{
+			expression:  `env("BUILDKITE_GITHUB_DEPLOYMENT_PAYLOAD") == "{}"`,
+			wantMessage: `reading "BUILDKITE_GITHUB_DEPLOYMENT_PAYLOAD" is denied by the environment policy`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			err := Validate(tt.expression, Context{}, policy)
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("Validate(%q) returned error: %v", tt.expression, err)
+				}
+				return
+			}
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("Validate(%q) error = %v, want %s", tt.expression, err, ErrorKindValidation)
+			}
+			if !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Fatalf("Validate(%q) error = %q, want it to contain %q", tt.expression, err, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestEnvPolicyRejectsDynamicNamesAtRuntime(t *testing.T) {
+	policy := WithEnvPolicy(EnvPolicy{Allow: []string{"CI_FLAG_*"}, Deny: []string{"*_PASSWORD"}})
+	ctx := Context{BuildEnv: map[string]string{
+		"CI_FLAG_TARGET":   "DB_PASSWORD",
+		"CI_FLAG_FALLBACK": "CI_FLAG_DEPLOY",
+		"CI_FLAG_DEPLOY":   "true",
+		"DB_PASSWORD":      "hunter2",
+	}}
+
+	expression := `env($CI_FLAG_TARGET) == "hunter2"`
+	_, err := Evaluate(expression, ctx, policy)
+	if !IsErrorKind(err, ErrorKindEvaluation) {
+		t.Fatalf("Evaluate(%q) error = %v, want %s", expression, err, ErrorKindEvaluation)
+	}
+	if !strings.Contains(err.Error(), `reading "DB_PASSWORD" is denied by the environment policy`) {
+		t.Fatalf("Evaluate(%q) error = %q, want a policy violation", expression, err)
+	}
+
+	expression = `env($CI_FLAG_FALLBACK) == "true"`
+	got, err := Evaluate(expression, ctx, policy)
+	if err != nil {
+		t.Fatalf("Evaluate(%q) returned error: %v", expression, err)
+	}
+	if !got {
+		t.Fatalf("Evaluate(%q) = false, want true", expression)
+	}
+}
+
+func TestWithEnvPolicyRejectsInvalidPatterns(t *testing.T) {
+	_, err := NewEvaluator(WithEnvPolicy(EnvPolicy{Deny: []string{"[DB"}}))
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+	}
+}
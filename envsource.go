@@ -0,0 +1,93 @@
+package conditional
+
+// EnvSource provides environment values to env(), build.env() and shell
+// expansions such as $NAME and ${NAME:-fallback}. Values are looked up only
+// when an expression reads them, so a source can be backed by a lazy store or
+// wrap another source to record which names were read. A source shared by an
+// Evaluator must be safe for concurrent use.
+type EnvSource interface {
+	LookupEnv(name string) (string, bool)
+}
+
+// EnvMap is an EnvSource backed by a map.
+type EnvMap map[string]string
+
+// LookupEnv returns the value of name and whether it is set.
+func (m EnvMap) LookupEnv(name string) (string, bool) {
+	value, ok := m[name]
+	return value, ok
+}
+
+// LayeredEnv returns an EnvSource that combines layers, such as organization,
+// pipeline and build environment, in that order. A name set in a later layer
+// overrides the same name in earlier layers.
+func LayeredEnv(layers ...EnvSource) EnvSource {
+	return layeredEnv(layers)
+}
+
+type layeredEnv []EnvSource
+
+func (l layeredEnv) LookupEnv(name string) (string, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i] == nil {
+			continue
+		}
+		if value, ok := l[i].LookupEnv(name); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// WithEnvSource adds an environment source beneath Context.ProjectEnv and
+// Context.BuildEnv, which override it. Sources added later override sources
+// added earlier, and built-in BUILDKITE_* values derived from Context override
+// every source. As with Context environment, unsupported BUILDKITE_* names are
+// never read from a source.
+func WithEnvSource(source EnvSource) Option {
+	return func(options *optionSet) error {
+		if source == nil {
+			return validationError("nil environment source")
+		}
+		options.envSources = append(options.envSources, source)
+		return nil
+	}
+}
+
+// environment resolves names for a single evaluation without copying the
+// caller's environment. Built-in values come first, then Context.BuildEnv,
+// Context.ProjectEnv and the configured sources.
+type environment struct {
+	builtin  map[string]string
+	layers   layeredEnv
+	redactor *redactor
+	policy   *EnvPolicy
+}
+
+func newEnvironment(ctx Context, options optionSet) environment {
+	layers := make(layeredEnv, 0, len(options.envSources)+2)
+	layers = append(layers, options.envSources...)
+	layers = append(layers, EnvMap(ctx.ProjectEnv), EnvMap(ctx.BuildEnv))
+	return environment{builtin: builtinEnv(ctx), layers: layers, redactor: options.redactor(), policy: options.envPolicy}
+}
+
+func (e environment) LookupEnv(name string) (string, bool) {
+	value, ok := e.lookup(name)
+	if ok {
+		e.redactor.record(name, value)
+	}
+	return value, ok
+}
+
+func (e environment) lookup(name string) (string, bool) {
+	if e.policy.violation(name) != "" {
+		return "", false
+	}
+	if value, ok := e.builtin[name]; ok {
+		return value, true
+	}
+	if unsupportedBuildkiteEnv(name) {
+		return "", false
+	}
+	return e.layers.LookupEnv(name)
+}
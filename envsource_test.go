@@ -0,0 +1,105 @@
+package conditional
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingEnv struct {
+	EnvSource
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *recordingEnv) LookupEnv(name string) (string, bool) {
+	r.mu.Lock()
+	r.names = append(r.names, name)
+	r.mu.Unlock()
+	return r.EnvSource.LookupEnv(name)
+}
+
+func TestEnvSourceOption(t *testing.T) {
+	org := EnvMap{"DEPLOY_TARGET": "staging", "REGION": "us-east-1", "TEAM": "platform"}
+	pipeline := EnvMap{"DEPLOY_TARGET": "production", "BUILDKITE_AGENT_ACCESS_TOKEN": "secret"}
+	evaluator, err := NewEvaluator(WithEnvSource(LayeredEnv(org, pipeline)))
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	ctx := Context{
+		Build:    Build{Branch: str("main")},
+		BuildEnv: map[string]string{"TEAM": "payments", "BUILDKITE_BRANCH": "ignored"},
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `env("DEPLOY_TARGET") == "production"`, want: true},
+		{expression: `build.env("REGION") == "us-east-1"`, want: true},
+		{expression: `$REGION == "us-east-1"`, want: true},
+		{expression: `${MISSING:-fallback} == "fallback"`, want: true},
+		{expression: `"${DEPLOY_TARGET}-${REGION}" == "production-us-east-1"`, want: true},
+		{expression: `env("TEAM") == "payments"`, want: true},
+		{expression: `build.env("MISSING") == null`, want: true},
+		{expression: `build.env("BUILDKITE_BRANCH") == "main"`, want: true},
+		{expression: `$BUILDKITE_AGENT_ACCESS_TOKEN == null`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := evaluator.Evaluate(tt.expression, ctx)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) returned error: %v", tt.expression, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Evaluate(%q) = %t, want %t", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvSourceIsReadLazily(t *testing.T) {
+	source := &recordingEnv{EnvSource: EnvMap{"DEPLOY_TARGET": "production", "UNUSED": "value"}}
+	evaluator, err := NewEvaluator(WithEnvSource(source))
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	expression := `env("DEPLOY_TARGET") == "production" && ${REGION:-us} == "us"`
+	got, err := evaluator.Evaluate(expression, Context{})
+	if err != nil {
+		t.Fatalf("Evaluate(%q) returned error: %v", expression, err)
+	}
+	if !got {
+		t.Fatalf("Evaluate(%q) = false, want true", expression)
+	}
+	if strings.Join(source.names, ",") != "DEPLOY_TARGET,REGION" {
+		t.Fatalf("source read %v, want [DEPLOY_TARGET REGION]", source.names)
+	}
+}
+
+func TestLaterEnvSourcesOverrideEarlierSources(t *testing.T) {
+	evaluator, err := NewEvaluator(
+		WithEnvSource(EnvMap{"DEPLOY_TARGET": "staging"}),
+		WithEnvSource(EnvMap{"DEPLOY_TARGET": "production"}),
+	)
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	got, err := evaluator.Evaluate(`env("DEPLOY_TARGET") == "production"`, Context{})
+	if err != nil {
+		t.Fatalf("Evaluate returned error: %v", err)
+	}
+	if !got {
+		t.Fatal("Evaluate = false, want the later source to win")
+	}
+}
+
+func TestWithEnvSourceRejectsNil(t *testing.T) {
+	_, err := NewEvaluator(WithEnvSource(nil))
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+	}
+}
@@ -3,6 +3,9 @@ package conditional
 import (
 	"errors"
 	"fmt"
+
+	"github.com/buildkite/conditional/internal/parser"
+	"github.com/buildkite/conditional/internal/regex"
 )
 
 // ErrorKind classifies conditional failures without depending on exact server
@@ -18,6 +21,12 @@ const (
 	ErrorKindEvaluation ErrorKind = "evaluation"
 	// ErrorKindResult indicates that the expression did not evaluate to a bool.
 	ErrorKindResult ErrorKind = "result"
+	// ErrorKindLimit indicates that the expression exceeded a resource limit
+	// such as WithMaxExpressionLength.
+	ErrorKindLimit ErrorKind = "limit"
+	// ErrorKindTimeout indicates that a regular expression match ran past the
+	// WithRegexTimeout deadline.
+	ErrorKindTimeout ErrorKind = "timeout"
 )
 
 // Error is a typed conditional error. Cause contains a lower-level error when
@@ -46,6 +55,49 @@ func (e *Error) Is(target error) bool {
 	return ok && e.Kind == targetError.Kind
 }
 
+// RegexFeatureError reports a regular expression construct that Buildkite does
+// not support. It is the Cause of the ErrorKindParse Error returned for the
+// expression, and can be retrieved with errors.As.
+type RegexFeatureError struct {
+	// Pattern is the regular expression source between the slashes.
+	Pattern string
+	// Feature describes the construct, such as "negative lookbehind `(?<!`".
+	Feature string
+	// Offset is the byte offset of the construct within Pattern.
+	Offset int
+	// Pos is the byte offset of the construct within the expression.
+	Pos int
+	// Suggestion describes the closest supported rewrite, when one exists,
+	// and how it differs.
+	Suggestion string
+}
+
+func (e *RegexFeatureError) Error() string {
+	message := fmt.Sprintf("/%s/ uses %s at offset %d, which Buildkite does not support", e.Pattern, e.Feature, e.Offset)
+	if e.Suggestion != "" {
+		message += "; " + e.Suggestion
+	}
+	return message
+}
+
+// regexFeatureError converts a parser regexp feature error into a
+// RegexFeatureError, returning err unchanged otherwise.
+func regexFeatureError(err error) error {
+	var regexpErr *parser.RegexpError
+	var featureErr *regex.FeatureError
+	if !errors.As(err, &regexpErr) || !errors.As(regexpErr.Err, &featureErr) {
+		return err
+	}
+	return &RegexFeatureError{
+		Pattern: regexpErr.Token.Literal,
+		Feature: featureErr.Name,
+		Offset:  featureErr.Offset,
+		// The pattern starts after the opening slash.
+		Pos:        regexpErr.Token.Pos + 1 + featureErr.Offset,
+		Suggestion: featureErr.Suggestion,
+	}
+}
+
 // IsErrorKind reports whether err contains a conditional Error with kind.
 func IsErrorKind(err error, kind ErrorKind) bool {
 	return errors.Is(err, &Error{Kind: kind})
@@ -87,3 +87,23 @@ func ExampleNewEvaluator() {
 	// Output:
 	// true
 }
+
+func ExampleWithVariable() {
+	oncall := conditional.WithVariable("team.oncall", conditional.StringType, func(ctx conditional.Context) conditional.Value {
+		return conditional.StringValue("alice")
+	})
+
+	ok, err := conditional.Evaluate(
+		`team.oncall == "alice"`,
+		conditional.Context{EntryPoint: conditional.EntryPointBuildCondition},
+		oncall,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(ok)
+
+	// Output:
+	// true
+}
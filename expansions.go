@@ -0,0 +1,118 @@
+package conditional
+
+import (
+	"fmt"
+
+	"github.com/buildkite/conditional/internal/ast"
+	"github.com/buildkite/conditional/internal/shell"
+)
+
+// lintExpansions warns about shell expansions, standalone or inside
+// double-quoted strings, that read variables conditionals cannot see or that
+// fail evaluation.
+func (l *linter) lintExpansions(expr ast.Expression) {
+	for _, child := range ast.Children(expr) {
+		l.lintExpansions(child)
+	}
+
+	for _, expansion := range shell.Expansions(expansionSource(expr)) {
+		l.lintExpansion(expr, expansion)
+	}
+}
+
+// expansionSource returns the raw text of a standalone shell expansion or a
+// double-quoted string that contains expansions, or "" for any other node.
+func expansionSource(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.ShellExpansion:
+		return expr.Raw
+	case *ast.StringLiteral:
+		if runtimeStringLiteral(expr) {
+			return expr.Token.Raw
+		}
+	}
+	return ""
+}
+
+// validateExpansions rejects the shell expansions lintExpansions warns about:
+// reads of BUILDKITE_* variables conditionals cannot see, literal substring
+// offsets that are not integers, and required expansions such as ${VAR:?}.
+// Evaluate accepts them, as Buildkite does, and reads null or fails at runtime.
+func validateExpansions(expr ast.Expression) error {
+	for _, child := range ast.Children(expr) {
+		if err := validateExpansions(child); err != nil {
+			return err
+		}
+	}
+
+	raw := expansionSource(expr)
+	for _, name := range shell.References(raw) {
+		if unsupportedRuntimeBuildkiteEnv(name) {
+			return unsupportedEnvError(name)
+		}
+	}
+	for _, expansion := range shell.Expansions(raw) {
+		if offsets := expansion.NonIntegerOffsets(); len(offsets) > 0 {
+			return validationError("`%s` has a non-integer substring offset %q", expansion.Raw, offsets[0])
+		}
+		switch expansion.Operator {
+		case "?":
+			return validationError("`%s` fails evaluation when %s is unset", expansion.Raw, expansion.Name)
+		case ":?":
+			return validationError("`%s` fails evaluation when %s is unset or empty", expansion.Raw, expansion.Name)
+		}
+	}
+	return nil
+}
+
+func (l *linter) lintExpansion(expr ast.Expression, expansion shell.Expansion) {
+	if unsupportedRuntimeBuildkiteEnv(expansion.Name) {
+		message := fmt.Sprintf("`%s` reads %s, which is not available to conditionals, so it is always unset", expansion.Raw, expansion.Name)
+		if suggestion := suggestBuildkiteEnv(expansion.Name); suggestion != "" {
+			message += fmt.Sprintf("; did you mean %s?", suggestion)
+		}
+		l.warnMessage(diagnosticUnsupportedEnv, expr, message)
+	}
+
+	for _, offset := range expansion.NonIntegerOffsets() {
+		l.warn(diagnosticSubstringOffset, expr, "`%s` has a non-integer substring offset %q, so evaluation fails when %s is set", expansion.Raw, offset, expansion.Name)
+	}
+
+	switch expansion.Operator {
+	case "?":
+		l.warn(diagnosticRequiredExpansion, expr, "`%s` fails evaluation when %s is unset", expansion.Raw, expansion.Name)
+	case ":?":
+		l.warn(diagnosticRequiredExpansion, expr, "`%s` fails evaluation when %s is unset or empty", expansion.Raw, expansion.Name)
+	}
+}
+
+// lintExpansionDefault warns when an expansion compared with an enumeration
+// falls back to a value that is not one of its members, such as
+// `build.state == "${STATE:-pased}"`.
+func (l *linter) lintExpansionDefault(expr *ast.InfixExpression) {
+	for _, pair := range [][2]ast.Expression{{expr.Left, expr.Right}, {expr.Right, expr.Left}} {
+		subject, other := pair[0], pair[1]
+		typ, err := l.checker.check(subject)
+		if err != nil || typ.enum == nil {
+			continue
+		}
+		expansion, ok := wholeExpansion(other)
+		if !ok {
+			continue
+		}
+		if fallback, ok := expansion.Default(); ok && !typ.enum.includes(fallback) {
+			l.warn(diagnosticInvalidDefault, expr, "`%s` falls back to %q, which is not a valid `%s`", expansion.Raw, fallback, identifierName(subject))
+		}
+	}
+}
+
+// wholeExpansion returns the expansion that makes up all of expr, either a
+// standalone expansion or a double-quoted string containing only one.
+func wholeExpansion(expr ast.Expression) (shell.Expansion, bool) {
+	raw := expansionSource(expr)
+	expansions := shell.Expansions(raw)
+	if len(expansions) == 0 || expansions[0].Raw != raw {
+		return shell.Expansion{}, false
+	}
+	return expansions[0], true
+}
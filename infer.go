@@ -0,0 +1,130 @@
+package conditional
+
+import (
+	"strings"
+
+	"github.com/buildkite/conditional/internal/ast"
+)
+
+// UnknownReason explains why a value can only be known at runtime.
+type UnknownReason string
+
+const (
+	// UnknownShellExpansion marks a shell expansion such as $BRANCH, which
+	// reads the build environment at runtime.
+	UnknownShellExpansion UnknownReason = "shell expansion"
+	// UnknownRuntimeString marks a double-quoted string containing a shell
+	// expansion, which is expanded at runtime.
+	UnknownRuntimeString UnknownReason = "runtime string"
+)
+
+// TypedExpression is a sub-expression annotated with its inferred type.
+type TypedExpression struct {
+	// Expression is the formatted sub-expression.
+	Expression string
+	// Start and End are byte offsets of the sub-expression in the source.
+	// Grouping parentheses are not included.
+	Start int
+	End   int
+	// Type is the kind of value. Enumerations report StringType and set
+	// EnumName and EnumValues.
+	Type       ValueType
+	EnumName   string
+	EnumValues []string
+	// Unknown is set when the value depends on the environment at runtime,
+	// so it cannot be checked against an enumeration or compared statically.
+	Unknown  UnknownReason
+	Children []*TypedExpression
+}
+
+// InferTypes validates expression for the selected Buildkite context and
+// returns its syntax tree annotated with the type of every sub-expression. It
+// returns nil for an empty expression.
+func InferTypes(expression string, ctx Context, opts ...Option) (*TypedExpression, error) {
+	options, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return inferTypes(expression, ctx, options)
+}
+
+// InferTypes returns the type-annotated syntax tree of expression using the
+// evaluator's options.
+func (e Evaluator) InferTypes(expression string, ctx Context) (*TypedExpression, error) {
+	return inferTypes(expression, ctx, e.options)
+}
+
+func inferTypes(expression string, ctx Context, options optionSet) (*TypedExpression, error) {
+	entryPoint, err := normalizeEntryPoint(ctx.EntryPoint)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(expression) == "" {
+		return nil, nil
+	}
+
+	expr, err := parse(expression, options)
+	if err != nil {
+		return nil, err
+	}
+	ctx.EntryPoint = entryPoint
+	if err := validateExpression(expr, ctx, options); err != nil {
+		return nil, err
+	}
+
+	checker := newTypeChecker(ctx, options)
+	checker.types = map[ast.Expression]valueType{}
+	if _, err := checker.check(expr); err != nil {
+		return nil, err
+	}
+	return typedExpression(expr, checker.types), nil
+}
+
+func typedExpression(expr ast.Expression, types map[ast.Expression]valueType) *TypedExpression {
+	typ, ok := types[expr]
+	if !ok {
+		typ = valueType{kind: kindUnknown}
+	}
+
+	typed := &TypedExpression{
+		Expression: expr.String(),
+		Start:      expr.Pos(),
+		End:        expr.End(),
+		Type:       ValueType(typ.kind),
+		Unknown:    unknownReason(expr),
+	}
+	if typ.enum != nil {
+		typed.EnumName = typ.enum.name
+		typed.EnumValues = typ.enum.members()
+	}
+	for _, child := range ast.Children(expr) {
+		typed.Children = append(typed.Children, typedExpression(child, types))
+	}
+	return typed
+}
+
+func unknownReason(expr ast.Expression) UnknownReason {
+	switch expr := expr.(type) {
+	case *ast.ShellExpansion:
+		return UnknownShellExpansion
+	case *ast.StringLiteral:
+		if runtimeStringLiteral(expr) {
+			return UnknownRuntimeString
+		}
+	}
+	return ""
+}
+
+// At returns the innermost sub-expression whose span contains offset, or nil
+// when offset is outside t.
+func (t *TypedExpression) At(offset int) *TypedExpression {
+	if t == nil || offset < t.Start || offset >= t.End {
+		return nil
+	}
+	for _, child := range t.Children {
+		if found := child.At(offset); found != nil {
+			return found
+		}
+	}
+	return t
+}
@@ -0,0 +1,122 @@
+package conditional
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInferTypesAnnotatesEverySubExpression(t *testing.T) {
+	expression := `build.state == "passed" && $DEPLOY == "yes"`
+	typed, err := InferTypes(expression, Context{})
+	if err != nil {
+		t.Fatalf("InferTypes returned error: %v", err)
+	}
+
+	if typed.Type != BoolType || typed.Start != 0 || typed.End != len(expression) {
+		t.Fatalf("root = %+v, want boolean spanning the expression", typed)
+	}
+
+	state := typed.Children[0].Children[0]
+	if state.Expression != "build.state" || state.Type != StringType || state.EnumName != "build state" {
+		t.Fatalf("build.state = %+v, want build state enumeration", state)
+	}
+	if !reflect.DeepEqual(state.EnumValues, enumValuesOf(t, "build.state")) {
+		t.Fatalf("build.state enum values = %v", state.EnumValues)
+	}
+	if expression[state.Start:state.End] != "build.state" {
+		t.Fatalf("build.state span = %d:%d", state.Start, state.End)
+	}
+
+	deploy := typed.Children[1].Children[0]
+	if deploy.Type != StringType || deploy.Unknown != UnknownShellExpansion {
+		t.Fatalf("$DEPLOY = %+v, want string from a shell expansion", deploy)
+	}
+	if literal := typed.Children[1].Children[1]; literal.Unknown != "" {
+		t.Fatalf(`"yes" = %+v, want a static string`, literal)
+	}
+}
+
+func TestInferTypesMarksRuntimeStrings(t *testing.T) {
+	typed, err := InferTypes(`build.branch == "release-${VERSION}"`, Context{})
+	if err != nil {
+		t.Fatalf("InferTypes returned error: %v", err)
+	}
+	if got := typed.Children[1].Unknown; got != UnknownRuntimeString {
+		t.Fatalf("runtime string unknown = %q, want %q", got, UnknownRuntimeString)
+	}
+
+	typed, err = InferTypes(`build.branch == 'release-${VERSION}'`, Context{})
+	if err != nil {
+		t.Fatalf("InferTypes returned error: %v", err)
+	}
+	if got := typed.Children[1].Unknown; got != "" {
+		t.Fatalf("single-quoted string unknown = %q, want static", got)
+	}
+}
+
+func TestInferTypesAtFindsInnermostExpression(t *testing.T) {
+	expression := `build.env("DEPLOY") == "yes" ? build.pull_request.labels includes "ship" : false`
+	typed, err := InferTypes(expression, Context{})
+	if err != nil {
+		t.Fatalf("InferTypes returned error: %v", err)
+	}
+
+	tests := []struct {
+		offset int
+		want   string
+		typ    ValueType
+	}{
+		{offset: 2, want: `build.env("DEPLOY")`, typ: StringType},
+		{offset: 11, want: `"DEPLOY"`, typ: StringType},
+		{offset: 22, want: `build.env("DEPLOY") == "yes"`, typ: BoolType},
+		{offset: 35, want: `build.pull_request.labels`, typ: StringArrayType},
+		{offset: len(expression) - 1, want: `false`, typ: BoolType},
+	}
+	for _, tt := range tests {
+		found := typed.At(tt.offset)
+		if found == nil || expression[found.Start:found.End] != tt.want || found.Type != tt.typ {
+			t.Fatalf("At(%d) = %+v, want %q of type %s", tt.offset, found, tt.want, tt.typ)
+		}
+	}
+	if found := typed.At(len(expression)); found != nil {
+		t.Fatalf("At(end) = %+v, want nil", found)
+	}
+}
+
+func TestInferTypesReturnsValidationErrors(t *testing.T) {
+	if _, err := InferTypes(`build.brnach == "main"`, Context{}); !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("InferTypes error = %v, want validation error", err)
+	}
+	if typed, err := InferTypes(" ", Context{}); typed != nil || err != nil {
+		t.Fatalf("InferTypes(blank) = %+v, %v, want nil, nil", typed, err)
+	}
+}
+
+func TestEvaluatorInferTypesIncludesCustomVariables(t *testing.T) {
+	evaluator, err := NewEvaluator(WithVariable("deploy.environment", EnumType("deploy environment", "staging", "production"), func(Context) Value {
+		return StringValue("staging")
+	}))
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	typed, err := evaluator.InferTypes(`deploy.environment == "staging"`, Context{})
+	if err != nil {
+		t.Fatalf("InferTypes returned error: %v", err)
+	}
+	if got := typed.Children[0]; got.EnumName != "deploy environment" || !reflect.DeepEqual(got.EnumValues, []string{"production", "staging"}) {
+		t.Fatalf("deploy.environment = %+v, want deploy environment enumeration", got)
+	}
+}
+
+func enumValuesOf(t *testing.T, name string) []string {
+	t.Helper()
+
+	for _, variable := range Variables(EntryPointBuildCondition) {
+		if variable.Name == name {
+			return variable.EnumValues
+		}
+	}
+	t.Fatalf("no variable %s", name)
+	return nil
+}
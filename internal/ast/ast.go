@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/buildkite/conditional/internal/regex"
 	"github.com/buildkite/conditional/internal/token"
-	"github.com/dlclark/regexp2"
 )
 
 // The base Node interface
 type Node interface {
 	TokenLiteral() string
 	String() string
+	// Pos and End are byte offsets of the node's first character and just
+	// past its last character. Grouping parentheses are not part of a node.
+	Pos() int
+	End() int
 }
 
 // All expression nodes implement this
@@ -29,6 +33,8 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() int             { return i.Token.Pos }
+func (i *Identifier) End() int             { return i.Token.End }
 func (i *Identifier) String() string       { return i.Value }
 
 type Boolean struct {
@@ -38,6 +44,8 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() int             { return b.Token.Pos }
+func (b *Boolean) End() int             { return b.Token.End }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 type Null struct {
@@ -46,6 +54,8 @@ type Null struct {
 
 func (n *Null) expressionNode()      {}
 func (n *Null) TokenLiteral() string { return n.Token.Literal }
+func (n *Null) Pos() int             { return n.Token.Pos }
+func (n *Null) End() int             { return n.Token.End }
 func (n *Null) String() string       { return n.Token.Literal }
 
 type IntegerLiteral struct {
@@ -55,6 +65,8 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() int             { return il.Token.Pos }
+func (il *IntegerLiteral) End() int             { return il.Token.End }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
 type StringLiteral struct {
@@ -64,18 +76,22 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() int             { return sl.Token.Pos }
+func (sl *StringLiteral) End() int             { return sl.Token.End }
 func (sl *StringLiteral) String() string {
 	return fmt.Sprintf("%q", sl.Token.Literal)
 }
 
 type Regexp struct {
-	*regexp2.Regexp
+	*regex.Regexp
 	Token token.Token
 	Flags string
 }
 
 func (r *Regexp) expressionNode()      {}
 func (r *Regexp) TokenLiteral() string { return r.Token.Literal }
+func (r *Regexp) Pos() int             { return r.Token.Pos }
+func (r *Regexp) End() int             { return r.Token.End }
 func (r *Regexp) String() string {
 	return fmt.Sprintf("/%s/%s", r.Token.Literal, r.Flags)
 }
@@ -87,16 +103,26 @@ type ShellExpansion struct {
 
 func (se *ShellExpansion) expressionNode()      {}
 func (se *ShellExpansion) TokenLiteral() string { return se.Token.Literal }
+func (se *ShellExpansion) Pos() int             { return se.Token.Pos }
+func (se *ShellExpansion) End() int             { return se.Token.End }
 func (se *ShellExpansion) String() string       { return se.Raw }
 
 type PrefixExpression struct {
 	Token    token.Token // The prefix token, e.g. !
 	Operator string
 	Right    Expression
+	Last     token.Token // The operand's last token, e.g. the ) of a group
 }
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() int             { return pe.Token.Pos }
+func (pe *PrefixExpression) End() int {
+	if pe.Last.End > pe.Right.End() {
+		return pe.Last.End
+	}
+	return pe.Right.End()
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -117,6 +143,8 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() int             { return ie.Left.Pos() }
+func (ie *InfixExpression) End() int             { return ie.Right.End() }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -144,6 +172,8 @@ type ConditionalExpression struct {
 
 func (ce *ConditionalExpression) expressionNode()      {}
 func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) Pos() int             { return ce.Condition.Pos() }
+func (ce *ConditionalExpression) End() int             { return ce.Alternative.End() }
 func (ce *ConditionalExpression) String() string {
 	var out bytes.Buffer
 
@@ -162,10 +192,14 @@ type CallExpression struct {
 	Token     token.Token // The '(' token
 	Function  string
 	Arguments []Expression
+	NamePos   int         // byte offset of the function name
+	Close     token.Token // The ')' token
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() int             { return ce.NamePos }
+func (ce *CallExpression) End() int             { return ce.Close.End }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -185,10 +219,13 @@ func (ce *CallExpression) String() string {
 type ArrayLiteral struct {
 	Token    token.Token // the '[' token
 	Elements []Expression
+	Close    token.Token // the ']' token
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() int             { return al.Token.Pos }
+func (al *ArrayLiteral) End() int             { return al.Close.End }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -203,3 +240,21 @@ func (al *ArrayLiteral) String() string {
 
 	return out.String()
 }
+
+// Children returns the direct sub-expressions of expr in source order.
+func Children(expr Expression) []Expression {
+	switch expr := expr.(type) {
+	case *PrefixExpression:
+		return []Expression{expr.Right}
+	case *InfixExpression:
+		return []Expression{expr.Left, expr.Right}
+	case *ConditionalExpression:
+		return []Expression{expr.Condition, expr.Consequence, expr.Alternative}
+	case *CallExpression:
+		return expr.Arguments
+	case *ArrayLiteral:
+		return expr.Elements
+	default:
+		return nil
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/buildkite/conditional/internal/ast"
 	"github.com/buildkite/conditional/internal/object"
+	"github.com/buildkite/conditional/internal/regex"
 )
 
 var (
@@ -146,6 +147,8 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	// defer untrace(trace("evalInfixExpression", operator, left, right))
 
 	switch {
+	case operator == "in":
+		return evalMembershipExpression(left, right)
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
@@ -226,6 +229,14 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
@@ -254,9 +265,9 @@ func evalStringRegexpInfixExpression(operator string, left, right object.Object)
 
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.Regexp).Regexp
-	matched, err := rightVal.MatchString(leftVal)
+	matched, err := regex.MatchString(rightVal, leftVal)
 	if err != nil {
-		return newError("regexp match failed: %s", err)
+		return wrapError(err, "regexp match failed: %s", err)
 	}
 
 	switch operator {
@@ -296,9 +307,9 @@ func arrayContains(arr *object.Array, obj object.Object) (bool, error) {
 				return false, fmt.Errorf("type mismatch at index %d in array: %s vs STRING",
 					idx, el.Type())
 			}
-			matched, err := regexpObj.MatchString(stringObj.Value)
+			matched, err := regex.MatchString(regexpObj.Regexp, stringObj.Value)
 			if err != nil {
-				return false, fmt.Errorf("regexp match failed: %s", err)
+				return false, fmt.Errorf("regexp match failed: %w", err)
 			}
 			if matched {
 				return true, nil
@@ -320,6 +331,24 @@ func arrayContains(arr *object.Array, obj object.Object) (bool, error) {
 	return false, nil
 }
 
+func evalMembershipExpression(left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Null:
+		return FALSE
+	case *object.Array:
+		if _, ok := left.(*object.Regexp); ok {
+			return newError("unknown operator: %s in %s", left.Type(), right.Type())
+		}
+		contains, err := arrayContains(right, left)
+		if err != nil {
+			return wrapError(err, "%s", err.Error())
+		}
+		return nativeBoolToBooleanObject(contains)
+	default:
+		return newError("unknown operator: %s in %s", left.Type(), right.Type())
+	}
+}
+
 func evalArrayInfixExpression(operator string, left, right object.Object) object.Object {
 	// defer untrace(trace("evalStringArrayInfixExpression", operator, left, right))
 
@@ -333,7 +362,7 @@ func evalArrayInfixExpression(operator string, left, right object.Object) object
 	case "includes":
 		contains, err := arrayContains(leftVal, right)
 		if err != nil {
-			return newError("%s", err.Error())
+			return wrapError(err, "%s", err.Error())
 		}
 		return nativeBoolToBooleanObject(contains)
 	default:
@@ -364,6 +393,10 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+func wrapError(cause error, format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Cause: cause}
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
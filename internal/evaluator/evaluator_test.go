@@ -56,6 +56,55 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestEvalIntegerOrdering(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 < 2", true},
+		{"2 < 1", false},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"2 > 1", true},
+		{"1 > 2", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, lexer.WithOrderingOperators())
+		p := parser.New(l)
+		expr := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+		testBooleanObject(t, Eval(expr, object.Struct{}), tt.expected)
+	}
+}
+
+func TestEvalMembershipOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" in ["a", "b"]`, true},
+		{`"c" in ["a", "b"]`, false},
+		{`"a" in []`, false},
+		{`null in ["a"]`, false},
+		{`"a" in null`, false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, lexer.WithMembershipOperator())
+		p := parser.New(l)
+		expr := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+		testBooleanObject(t, Eval(expr, object.Struct{}), tt.expected)
+	}
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
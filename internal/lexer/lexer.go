@@ -12,20 +12,51 @@ type Lexer struct {
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
 	ch           byte // current char under examination
+
+	ordering   bool // lex < <= > >= as operators instead of ILLEGAL
+	membership bool // lex in as an operator instead of an identifier
+}
+
+// Option configures language extensions beyond the Buildkite server grammar.
+type Option func(*Lexer)
+
+// WithOrderingOperators lexes <, <=, > and >= as ordering operators.
+func WithOrderingOperators() Option {
+	return func(l *Lexer) {
+		l.ordering = true
+	}
 }
 
-func New(input string) *Lexer {
+// WithMembershipOperator lexes in as the membership operator.
+func WithMembershipOperator() Option {
+	return func(l *Lexer) {
+		l.membership = true
+	}
+}
+
+func New(input string, opts ...Option) *Lexer {
 	l := &Lexer{input: input}
+	for _, opt := range opts {
+		opt(l)
+	}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
 	l.skipComment()
 
+	start := min(l.position, len(l.input))
+	tok := l.readToken()
+	tok.Pos = start
+	tok.End = min(l.position, len(l.input))
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	// log.Printf("Tok: %c %q", l.ch, l.ch)
 
 	switch l.ch {
@@ -77,6 +108,10 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
+	case '<':
+		tok = l.readOrdering(token.LT, token.LT_EQ)
+	case '>':
+		tok = l.readOrdering(token.GT, token.GT_EQ)
 	case '@':
 		tok = newToken(token.ILLEGAL, l.ch)
 	case '.':
@@ -136,6 +171,9 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			if l.membership && tok.Literal == token.IN {
+				tok.Type = token.IN
+			}
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = token.INT
@@ -266,6 +304,18 @@ func (l *Lexer) readShell() (string, bool) {
 	return raw, ok
 }
 
+func (l *Lexer) readOrdering(single, withEquals token.TokenType) token.Token {
+	if !l.ordering {
+		return newToken(token.ILLEGAL, l.ch)
+	}
+	if l.peekChar() == '=' {
+		ch := l.ch
+		l.readChar()
+		return token.Token{Type: withEquals, Literal: string(ch) + string(l.ch)}
+	}
+	return newToken(single, l.ch)
+}
+
 func (l *Lexer) readRegex() (string, string, bool) {
 	position := l.position + 1
 	escaped := false
@@ -178,6 +178,67 @@ func TestLexingRejectsAtGreaterOperator(t *testing.T) {
 	})
 }
 
+func TestLexingOrderingOperators(t *testing.T) {
+	input := `build.number > 1 && build.number >= 2 && build.number < 3 && build.number <= 4`
+	expect := []tokenExpectation{
+		{token.IDENT, "build.number"},
+		{token.GT, ">"},
+		{token.INT, "1"},
+		{token.AND, "&&"},
+		{token.IDENT, "build.number"},
+		{token.GT_EQ, ">="},
+		{token.INT, "2"},
+		{token.AND, "&&"},
+		{token.IDENT, "build.number"},
+		{token.LT, "<"},
+		{token.INT, "3"},
+		{token.AND, "&&"},
+		{token.IDENT, "build.number"},
+		{token.LT_EQ, "<="},
+		{token.INT, "4"},
+	}
+
+	l := New(input, WithOrderingOperators())
+	for i, tt := range expect {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("#%d - token wrong. expected=%q (%q), got=%q (%q)",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestLexingOrderingOperatorsDisabledByDefault(t *testing.T) {
+	expectTokens(t, `build.number < 1`, []tokenExpectation{
+		{token.IDENT, "build.number"},
+		{token.ILLEGAL, "<"},
+		{token.INT, "1"},
+	})
+}
+
+func TestLexingMembershipOperator(t *testing.T) {
+	l := New(`build.branch in ["main"]`, WithMembershipOperator())
+	expect := []tokenExpectation{
+		{token.IDENT, "build.branch"},
+		{token.IN, "in"},
+		{token.LBRACKET, "["},
+		{token.STRING, "main"},
+		{token.RBRACKET, "]"},
+		{token.EOF, ""},
+	}
+	for i, tt := range expect {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("#%d - token wrong. expected=%q (%q), got=%q (%q)",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	expectTokens(t, `in`, []tokenExpectation{
+		{token.IDENT, "in"},
+	})
+}
+
 func TestLexingTernaries(t *testing.T) {
 	expectTokens(t, `true ? false : true`, []tokenExpectation{
 		{token.TRUE, "true"},
@@ -212,6 +273,24 @@ func TestLexingUnterminatedShellExpansion(t *testing.T) {
 	})
 }
 
+func TestLexingRecordsTokenSpans(t *testing.T) {
+	input := `build.env("A") =~ /x/i // note
+  && [$B, 'c']`
+	l := New(input)
+
+	want := []string{`build.env`, `(`, `"A"`, `)`, `=~`, `/x/i`, `&&`, `[`, `$B`, `,`, `'c'`, `]`}
+	for i, text := range want {
+		tok := l.NextToken()
+		if got := input[tok.Pos:tok.End]; got != text {
+			t.Fatalf("#%d - span wrong. expected=%q, got=%q (%d:%d)", i, text, got, tok.Pos, tok.End)
+		}
+	}
+
+	if tok := l.NextToken(); tok.Type != token.EOF || tok.Pos != len(input) || tok.End != len(input) {
+		t.Fatalf("EOF token = %+v, want empty span at %d", tok, len(input))
+	}
+}
+
 func expectTokens(t *testing.T, input string, expect []tokenExpectation) {
 	t.Helper()
 	l := New(input)
@@ -6,7 +6,7 @@ import (
 	"reflect"
 	"strings"
 
-	"github.com/dlclark/regexp2"
+	"github.com/buildkite/conditional/internal/regex"
 )
 
 type ObjectType string
@@ -59,7 +59,7 @@ func (s *String) String() string       { return fmt.Sprintf("%q", s.Value) }
 func (s *String) Equals(o Object) bool { return s.String() == o.String() }
 
 type Regexp struct {
-	*regexp2.Regexp
+	*regex.Regexp
 	Flags string
 }
 
@@ -142,6 +142,8 @@ func (ao *Array) Equals(o Object) bool {
 
 type Error struct {
 	Message string
+	// Cause is the underlying Go error, when there is one.
+	Cause error
 }
 
 func (e *Error) Type() ObjectType     { return ERROR_OBJ }
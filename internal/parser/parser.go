@@ -16,12 +16,13 @@ const (
 	_ int = iota
 	LOWEST
 	TERNARY
-	OR     // ||
-	AND    // &&
-	EQUALS // ==
-	PREFIX // !X
-	CALL   // myfunction(true)
-	DOT    // foo.bar
+	OR          // ||
+	AND         // &&
+	EQUALS      // ==
+	LESSGREATER // > or <
+	PREFIX      // !X
+	CALL        // myfunction(true)
+	DOT         // foo.bar
 )
 
 var precedences = map[token.TokenType]int{
@@ -30,6 +31,11 @@ var precedences = map[token.TokenType]int{
 	token.RE_EQ:     EQUALS,
 	token.RE_NOT_EQ: EQUALS,
 	token.INCLUDES:  EQUALS,
+	token.IN:        EQUALS,
+	token.LT:        LESSGREATER,
+	token.LT_EQ:     LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.GT_EQ:     LESSGREATER,
 	token.AND:       AND,
 	token.OR:        OR,
 	token.QUESTION:  TERNARY,
@@ -45,6 +51,11 @@ type (
 type Parser struct {
 	l      *lexer.Lexer
 	errors []error
+	regex  regex.Compiler
+
+	// depth counts the parseExpression calls in progress, up to maxDepth.
+	depth    int
+	maxDepth int
 
 	curToken  token.Token
 	peekToken token.Token
@@ -53,11 +64,55 @@ type Parser struct {
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
-func New(l *lexer.Lexer) *Parser {
+// RegexpError reports a regexp literal that failed to compile.
+type RegexpError struct {
+	Token token.Token
+	Err   error
+}
+
+func (e *RegexpError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the compile error.
+func (e *RegexpError) Unwrap() error { return e.Err }
+
+// DepthError reports an expression nested more than WithMaxDepth levels.
+type DepthError struct {
+	Max int
+}
+
+func (e *DepthError) Error() string {
+	return fmt.Sprintf("expression is nested more than %d levels deep", e.Max)
+}
+
+// Option configures a Parser.
+type Option func(*Parser)
+
+// WithRegexCompiler compiles regexp literals with compiler instead of the
+// default limits.
+func WithRegexCompiler(compiler regex.Compiler) Option {
+	return func(p *Parser) {
+		p.regex = compiler
+	}
+}
+
+// WithMaxDepth stops parsing with a DepthError once expressions are nested more
+// than n levels deep, before deeply nested input can exhaust the stack. Each
+// operand, argument, array element and parenthesised group is a level. Zero
+// means no limit.
+func WithMaxDepth(n int) Option {
+	return func(p *Parser) {
+		p.maxDepth = n
+	}
+}
+
+func New(l *lexer.Lexer, opts ...Option) *Parser {
 	p := &Parser{
 		l:      l,
 		errors: []error{},
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
@@ -78,6 +133,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.RE_EQ, p.parseInfixExpression)
 	p.registerInfix(token.RE_NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.INCLUDES, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.LT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.AND, p.parseInfixExpression)
 	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
@@ -154,6 +214,17 @@ func (p *Parser) Parse() ast.Expression {
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	// defer untrace(trace("parseExpression", p.curToken))
 
+	if p.maxDepth > 0 {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > p.maxDepth {
+			if p.depth == p.maxDepth+1 {
+				p.errors = append(p.errors, &DepthError{Max: p.maxDepth})
+			}
+			return nil
+		}
+	}
+
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
@@ -230,9 +301,9 @@ func (p *Parser) parseShellExpansion() ast.Expression {
 func (p *Parser) parseRegexp() ast.Expression {
 	ar := &ast.Regexp{Token: p.curToken, Flags: p.curToken.Flags}
 
-	r, err := regex.Compile(p.curToken.Literal, p.curToken.Flags)
+	r, err := p.regex.Compile(p.curToken.Literal, p.curToken.Flags)
 	if err != nil {
-		p.errors = append(p.errors, err)
+		p.errors = append(p.errors, &RegexpError{Token: p.curToken, Err: err})
 		return nil
 	}
 	ar.Regexp = r
@@ -250,6 +321,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	p.nextToken()
 
 	expression.Right = p.parseExpression(PREFIX)
+	expression.Last = p.curToken
 
 	return expression
 }
@@ -322,8 +394,9 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		return nil
 	}
 
-	exp := &ast.CallExpression{Token: p.curToken, Function: name}
+	exp := &ast.CallExpression{Token: p.curToken, Function: name, NamePos: function.Pos()}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Close = p.curToken
 	return exp
 }
 
@@ -377,6 +450,7 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Close = p.curToken
 
 	return array
 }
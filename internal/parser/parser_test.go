@@ -317,6 +317,30 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestOrderingOperatorPrecedenceParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a > 1", "(a > 1)"},
+		{"a >= 1 && b < 2", "((a >= 1) && (b < 2))"},
+		{"a <= 1 == true", "((a <= 1) == true)"},
+		{"a || b > 1 ? c : d", "((a || (b > 1)) ? c : d)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, lexer.WithOrderingOperators())
+		p := New(l)
+		expr := p.Parse()
+		checkParserErrors(t, p)
+
+		actual := expr.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -553,6 +577,66 @@ func TestParserRejectsTrailingTokens(t *testing.T) {
 	}
 }
 
+func TestParserStopsPastMaxDepth(t *testing.T) {
+	tests := []string{
+		strings.Repeat("(", 100000) + "true" + strings.Repeat(")", 100000),
+		strings.Repeat("!", 100000) + "true",
+		strings.Repeat("[", 100000) + "true" + strings.Repeat("]", 100000),
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input), WithMaxDepth(16))
+		p.Parse()
+
+		var depthErrors int
+		for _, err := range p.Errors() {
+			if depthErr, ok := err.(*DepthError); ok && depthErr.Max == 16 {
+				depthErrors++
+			}
+		}
+		if depthErrors != 1 {
+			t.Fatalf("expected one depth error for %.8q..., got %v", input, p.Errors())
+		}
+	}
+}
+
+func TestExpressionSpans(t *testing.T) {
+	input := `!(build.env("A") == "a") ? ["x", $Y] includes "x" : false`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+
+	span := func(exp ast.Expression) string {
+		return input[exp.Pos():exp.End()]
+	}
+
+	conditional, ok := program.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.ConditionalExpression. got=%T", program)
+	}
+	if got := span(conditional); got != input {
+		t.Fatalf("conditional span wrong. got=%q", got)
+	}
+
+	prefix := conditional.Condition.(*ast.PrefixExpression)
+	if got := span(prefix); got != `!(build.env("A") == "a")` {
+		t.Fatalf("prefix span wrong. got=%q", got)
+	}
+	comparison := prefix.Right.(*ast.InfixExpression)
+	if got := span(comparison.Left); got != `build.env("A")` {
+		t.Fatalf("call span wrong. got=%q", got)
+	}
+
+	includes := conditional.Consequence.(*ast.InfixExpression)
+	if got := span(includes.Left); got != `["x", $Y]` {
+		t.Fatalf("array span wrong. got=%q", got)
+	}
+	if got := span(includes); got != `["x", $Y] includes "x"` {
+		t.Fatalf("infix span wrong. got=%q", got)
+	}
+}
+
 func testInfixExpression(t *testing.T, exp ast.Expression, left interface{},
 	operator string, right interface{}) bool {
 
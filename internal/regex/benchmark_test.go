@@ -0,0 +1,18 @@
+package regex
+
+import "testing"
+
+func BenchmarkCompile(bench *testing.B) {
+	for i := 0; i < bench.N; i++ {
+		_, _ = Compile(`^release\/v[0-9]+\.[0-9]+$`, "i")
+	}
+}
+
+func BenchmarkCompileCached(bench *testing.B) {
+	compiler := Compiler{Cache: NewCache(16)}
+	bench.ResetTimer()
+
+	for i := 0; i < bench.N; i++ {
+		_, _ = compiler.Compile(`^release\/v[0-9]+\.[0-9]+$`, "i")
+	}
+}
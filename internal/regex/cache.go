@@ -0,0 +1,87 @@
+package regex
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is a bounded, concurrency-safe LRU cache of compiled regexps. Compiled
+// regexps are safe for concurrent matching, so one entry is shared by every
+// caller that compiles the same pattern with the same flags and timeout.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[cacheKey]*list.Element
+	order   *list.List // front is most recently used
+	hits    uint64
+	misses  uint64
+}
+
+type cacheKey struct {
+	pattern      string
+	flags        string
+	matchTimeout time.Duration
+}
+
+type cacheEntry struct {
+	key    cacheKey
+	regexp *Regexp
+}
+
+// CacheStats reports cache usage.
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+	Size    int
+}
+
+// NewCache returns a cache holding at most size compiled regexps. A cache of
+// size zero stores nothing but still counts misses.
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    size,
+		entries: make(map[cacheKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Stats returns the cache's hit and miss counts and current occupancy.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len(), Size: c.size}
+}
+
+func (c *Cache) get(key cacheKey) (*Regexp, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).regexp, true
+}
+
+func (c *Cache) add(key cacheKey, r *Regexp) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Another caller may have compiled the same pattern concurrently.
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, regexp: r})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,133 @@
+package regex
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheReusesCompiledRegexps(t *testing.T) {
+	cache := NewCache(2)
+	compiler := Compiler{Cache: cache}
+
+	first, err := compiler.Compile(`^release/`, "")
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	second, err := compiler.Compile(`^release/`, "")
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if first != second {
+		t.Fatal("Compile did not reuse the cached regexp")
+	}
+	if _, err := compiler.Compile(`^release/`, "i"); err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+
+	want := CacheStats{Hits: 1, Misses: 2, Entries: 2, Size: 2}
+	if got := cache.Stats(); got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCacheKeysOnMatchTimeout(t *testing.T) {
+	cache := NewCache(2)
+
+	fast, err := Compiler{Cache: cache, MatchTimeout: 10 * time.Millisecond}.Compile(`a+`, "")
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	slow, err := Compiler{Cache: cache}.Compile(`a+`, "")
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if fast == slow {
+		t.Fatal("Compile shared a regexp across match timeouts")
+	}
+	if slow.MatchTimeout != MatchTimeout {
+		t.Fatalf("MatchTimeout = %v, want %v", slow.MatchTimeout, MatchTimeout)
+	}
+}
+
+func TestCacheChecksFlagsBeforeReuse(t *testing.T) {
+	cache := NewCache(2)
+	if _, err := (Compiler{Cache: cache, ExtendedFlags: true}).Compile(`^Fixes`, "m"); err != nil {
+		t.Fatalf("Compile with ExtendedFlags returned error: %v", err)
+	}
+
+	_, err := Compiler{Cache: cache}.Compile(`^Fixes`, "m")
+	var flagErr *FlagError
+	if !errors.As(err, &flagErr) || flagErr.Flag != 'm' {
+		t.Fatalf("Compile error = %v, want a FlagError for m", err)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewCache(2)
+	compiler := Compiler{Cache: cache}
+
+	for _, pattern := range []string{`a`, `b`, `a`, `c`, `a`, `b`} {
+		if _, err := compiler.Compile(pattern, ""); err != nil {
+			t.Fatalf("Compile(%q) returned error: %v", pattern, err)
+		}
+	}
+
+	// b is evicted by c, then a and b are cached again.
+	want := CacheStats{Hits: 2, Misses: 4, Entries: 2, Size: 2}
+	if got := cache.Stats(); got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCacheOfSizeZeroStoresNothing(t *testing.T) {
+	cache := NewCache(0)
+	compiler := Compiler{Cache: cache}
+
+	for range 2 {
+		if _, err := compiler.Compile(`a`, ""); err != nil {
+			t.Fatalf("Compile returned error: %v", err)
+		}
+	}
+
+	want := CacheStats{Misses: 2}
+	if got := cache.Stats(); got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCacheIsSafeForConcurrentUse(t *testing.T) {
+	cache := NewCache(4)
+	compiler := Compiler{Cache: cache}
+	patterns := []string{`^main$`, `^release/`, `^v\d+`, `skip`, `deploy`, `^feature/`}
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				pattern := patterns[(worker+i)%len(patterns)]
+				r, err := compiler.Compile(pattern, "")
+				if err != nil {
+					t.Errorf("Compile(%q) returned error: %v", pattern, err)
+					return
+				}
+				if _, err := MatchString(r, "release/1.0"); err != nil {
+					t.Errorf("MatchString returned error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	if stats.Hits+stats.Misses != 800 {
+		t.Fatalf("Stats() = %+v, want 800 lookups", stats)
+	}
+	if stats.Entries > 4 {
+		t.Fatalf("Stats() = %+v, want at most 4 entries", stats)
+	}
+}
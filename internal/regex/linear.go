@@ -0,0 +1,144 @@
+package regex
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Regexp is a compiled conditional regexp. Patterns whose semantics are
+// identical under Go's regexp package also carry a linear-time program, which
+// MatchString prefers over regexp2's backtracking engine.
+type Regexp struct {
+	*regexp2.Regexp
+	linear *regexp.Regexp
+}
+
+// Linear reports whether MatchString runs in linear time.
+func (r *Regexp) Linear() bool {
+	return r.linear != nil
+}
+
+// compileLinear returns a Go regexp for pattern when it matches exactly the
+// same strings as regexp2 in RE2 mode, and nil otherwise.
+func compileLinear(pattern string, flags string) *regexp.Regexp {
+	// regexp2 folds case with its own tables, so case-insensitive patterns
+	// always backtrack.
+	if flags != "" || !linearCompatible(pattern) {
+		return nil
+	}
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// linearCompatible reports whether pattern only uses constructs that regexp2's
+// RE2 mode and Go's regexp package interpret identically: literals, `.`,
+// anchors, alternation, plain and non-capturing groups, greedy and lazy
+// quantifiers, simple character classes and a small set of escapes. Anything
+// else, including backreferences, lookarounds, inline options, word
+// boundaries, Unicode classes and POSIX classes, is left to regexp2.
+func linearCompatible(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '\\':
+			if i+1 >= len(pattern) || !linearEscape(pattern[i+1]) {
+				return false
+			}
+			i++
+		case '(':
+			if hasPrefix(pattern, i, "(?") {
+				if !hasPrefix(pattern, i, "(?:") {
+					return false
+				}
+				i += 2
+			}
+		case '[':
+			end := linearClassEnd(pattern, i)
+			if end == -1 {
+				return false
+			}
+			i = end
+		case '{':
+			end := linearQuantifierEnd(pattern, i)
+			if end == -1 {
+				return false
+			}
+			i = end
+		case '}', ']':
+			// Both engines treat these as literals, but only by error recovery.
+			return false
+		}
+	}
+	return true
+}
+
+// linearEscape reports whether \ch means the same thing in both engines.
+// regexp2 reads some punctuation escapes, such as \' and \`, as anchors, so
+// only escaped metacharacters and common literal punctuation are accepted.
+func linearEscape(ch byte) bool {
+	return strings.IndexByte(`dDsSwWntrfv\.+*?()|[]{}^$/-#&~"!,:;=@%_`, ch) != -1
+}
+
+// linearClassEnd returns the offset of the ']' closing the character class
+// opened at start, or -1 when the class uses a construct whose meaning differs
+// between engines, such as a leading ']', a range with an escaped endpoint, a
+// POSIX class or .NET subtraction.
+func linearClassEnd(pattern string, start int) int {
+	i := start + 1
+	if i < len(pattern) && pattern[i] == '^' {
+		i++
+	}
+	if i < len(pattern) && pattern[i] == ']' {
+		return -1
+	}
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i+1 >= len(pattern) || !linearEscape(pattern[i+1]) {
+				return -1
+			}
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '-' {
+				return -1
+			}
+		case '-':
+			if i+1 < len(pattern) && pattern[i+1] == '\\' {
+				return -1
+			}
+		case '[':
+			return -1
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// linearQuantifierEnd returns the offset of the '}' closing the bounded
+// quantifier opened at start, or -1 when the braces are not a quantifier in
+// both engines. Go reads bounds with leading zeros as literal text.
+func linearQuantifierEnd(pattern string, start int) int {
+	if start == 0 {
+		return -1
+	}
+	for i := start + 1; i < len(pattern); i++ {
+		if pattern[i] != '}' {
+			continue
+		}
+		bounds := pattern[start+1 : i]
+		if !isQuantifierBounds(bounds) {
+			return -1
+		}
+		for _, bound := range strings.Split(bounds, ",") {
+			if len(bound) > 1 && bound[0] == '0' {
+				return -1
+			}
+		}
+		return i
+	}
+	return -1
+}
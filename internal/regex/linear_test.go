@@ -0,0 +1,118 @@
+package regex
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlclark/regexp2"
+)
+
+func TestCompileUsesLinearEngineWhenEquivalent(t *testing.T) {
+	tests := []struct {
+		pattern string
+		flags   string
+		want    bool
+	}{
+		{pattern: `^release\/v[0-9]+\.[0-9]+$`, want: true},
+		{pattern: `(?:main|master)$`, want: true},
+		{pattern: `^(a+)+b$`, want: true},
+		{pattern: `\[skip (ci|tests)\]`, want: true},
+		{pattern: `[^a-z\d]{2,}?`, want: true},
+		{pattern: `\[skip tests\]`, flags: "i", want: false},
+		{pattern: `(a)\1`, want: false},
+		{pattern: `a(?=b)`, want: false},
+		{pattern: `(?!a)b`, want: false},
+		{pattern: `(?i)main`, want: false},
+		{pattern: `\bmain\b`, want: false},
+		{pattern: `\p{L}+`, want: false},
+		{pattern: `[[:digit:]]+`, want: false},
+		{pattern: `[a-z-[aeiou]]`, want: false},
+		{pattern: `[]a]`, want: false},
+		{pattern: `a{,3}`, want: false},
+		{pattern: `a{01}`, want: false},
+		{pattern: `[\--\\]`, want: false},
+		{pattern: `\x41`, want: false},
+		{pattern: `main\'`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.flags, func(t *testing.T) {
+			compiled, err := Compile(tt.pattern, tt.flags)
+			if err != nil {
+				t.Fatalf("Compile returned error: %v", err)
+			}
+			if got := compiled.Linear(); got != tt.want {
+				t.Fatalf("Linear() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinearEngineAvoidsCatastrophicBacktracking(t *testing.T) {
+	compiled, err := Compiler{MatchTimeout: 10 * time.Millisecond}.Compile(`^(a+)+b$`, "")
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+
+	matched, err := MatchString(compiled, strings.Repeat("a", 4096))
+	if err != nil {
+		t.Fatalf("MatchString returned error: %v", err)
+	}
+	if matched {
+		t.Fatal("MatchString matched, want no match")
+	}
+}
+
+// FuzzLinearMatchesBacktracking checks that every pattern on the linear fast
+// path matches exactly the strings regexp2 matches.
+func FuzzLinearMatchesBacktracking(f *testing.F) {
+	seeds := []struct{ pattern, input string }{
+		{`^release\/v[0-9]+\.[0-9]+$`, "release/v1.2"},
+		{`^release\/v[0-9]+\.[0-9]+$`, "release/v1.2\n"},
+		{`main$`, "main\n"},
+		{`^$`, ""},
+		{`a.c`, "a\nc"},
+		{`\s+`, "\v"},
+		{`\w+\d*\W`, "é1!"},
+		{`[^\d\s]`, " "},
+		{`(a|ab)(c|bcd)(d*)`, "abcd"},
+		{`x*?y+?z??`, "xxyz"},
+		{`a{2,3}b{1}c{0,}`, "aaab"},
+		{`0{00}`, "0"},
+		{`[\--\\]`, "0"},
+		{`\'0'`, "'0'"},
+		{`[a-c\-\]]+`, "-]b"},
+		{"é+", "\xc3\xa9\xff"},
+		{".", "\xff"},
+		{`[^a]`, "\xed\xa0\x80"},
+	}
+	for _, seed := range seeds {
+		f.Add(seed.pattern, seed.input)
+	}
+
+	f.Fuzz(func(t *testing.T, pattern, input string) {
+		// Large repetition counts make compilation, not matching, dominate.
+		if len(pattern) > 64 || len(input) > 256 || !linearCompatible(pattern) {
+			return
+		}
+		linear, err := regexp.Compile(pattern)
+		if err != nil {
+			return
+		}
+		backtracking, err := regexp2.Compile(pattern, regexp2.RE2)
+		if err != nil {
+			return
+		}
+		backtracking.MatchTimeout = 100 * time.Millisecond
+
+		want, err := backtracking.MatchString(input)
+		if err != nil {
+			return
+		}
+		if got := linear.MatchString(input); got != want {
+			t.Fatalf("pattern %q on %q: linear = %t, regexp2 = %t", pattern, input, got, want)
+		}
+	})
+}
@@ -1,7 +1,9 @@
 package regex
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/dlclark/regexp2"
@@ -10,34 +12,134 @@ import (
 // MatchTimeout bounds regexp2 backtracking for conditional regex matches.
 const MatchTimeout = time.Second
 
+var (
+	// ErrPatternTooLong reports a pattern over Compiler.MaxPatternLength.
+	ErrPatternTooLong = errors.New("regexp pattern too long")
+	// ErrMatchTimeout reports a match that ran past the compiled timeout.
+	ErrMatchTimeout = errors.New("regexp match timed out")
+)
+
+// Compiler compiles server-compatible conditional regexps with resource
+// limits. The zero value uses MatchTimeout and accepts patterns of any length.
+type Compiler struct {
+	// MatchTimeout bounds each match. Zero uses MatchTimeout.
+	MatchTimeout time.Duration
+	// MaxPatternLength is the longest accepted pattern in bytes. Zero means
+	// no limit.
+	MaxPatternLength int
+	// Cache, when set, reuses compiled regexps across Compile calls.
+	Cache *Cache
+	// ExtendedFlags accepts the m and x flags, which the Buildkite server
+	// rejects.
+	ExtendedFlags bool
+}
+
+// FlagError reports an unsupported regexp flag.
+type FlagError struct {
+	Flag rune
+	// Extended reports whether the flag is accepted with
+	// Compiler.ExtendedFlags.
+	Extended bool
+}
+
+func (e *FlagError) Error() string {
+	return fmt.Sprintf("unsupported regexp flag: %c", e.Flag)
+}
+
+// Compile validates and compiles a server-compatible conditional regexp with
+// the default limits.
+func Compile(pattern string, flags string) (*Regexp, error) {
+	return Compiler{}.Compile(pattern, flags)
+}
+
 // Compile validates and compiles a server-compatible conditional regexp.
-func Compile(pattern string, flags string) (*regexp2.Regexp, error) {
-	options, err := options(flags)
+func (c Compiler) Compile(pattern string, flags string) (*Regexp, error) {
+	if c.MaxPatternLength > 0 && len(pattern) > c.MaxPatternLength {
+		return nil, fmt.Errorf("%w: %d bytes, over the limit of %d", ErrPatternTooLong, len(pattern), c.MaxPatternLength)
+	}
+	matchTimeout := MatchTimeout
+	if c.MatchTimeout > 0 {
+		matchTimeout = c.MatchTimeout
+	}
+	// Check flags before the cache, which is shared by compilers that accept
+	// different flags.
+	options, err := options(flags, c.ExtendedFlags)
 	if err != nil {
 		return nil, err
 	}
+	key := cacheKey{pattern: pattern, flags: flags, matchTimeout: matchTimeout}
+	if c.Cache != nil {
+		if r, ok := c.Cache.get(key); ok {
+			return r, nil
+		}
+	}
+
 	if err := Validate(pattern); err != nil {
 		return nil, err
 	}
 
-	r, err := regexp2.Compile(pattern, options)
+	backtracking, err := regexp2.Compile(pattern, options)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse regexp: %v", err)
 	}
 	// regexp2 is intentionally used for Buildkite server-side syntax parity.
 	// It can backtrack, so keep matching bounded.
-	r.MatchTimeout = MatchTimeout
+	backtracking.MatchTimeout = matchTimeout
+	r := &Regexp{Regexp: backtracking, linear: compileLinear(pattern, flags)}
+	if c.Cache != nil {
+		c.Cache.add(key, r)
+	}
 	return r, nil
 }
 
-func options(flags string) (regexp2.RegexOptions, error) {
+// MatchString reports whether r matches s, in linear time when r.Linear().
+// regexp2 only fails a match when it times out, so every error wraps
+// ErrMatchTimeout.
+func MatchString(r *Regexp, s string) (bool, error) {
+	if r.linear != nil {
+		return r.linear.MatchString(s), nil
+	}
+	matched, err := r.Regexp.MatchString(s)
+	if err != nil {
+		return false, fmt.Errorf("%w after %s", ErrMatchTimeout, r.MatchTimeout)
+	}
+	return matched, nil
+}
+
+// FindStringSubmatch returns the text of the leftmost match of r in s and of
+// its capture groups, or nil when r does not match. Groups that did not take
+// part in the match are empty. Submatches always come from regexp2, as Go's
+// regexp reports different captures for repeated groups that match empty.
+func FindStringSubmatch(r *Regexp, s string) ([]string, error) {
+	match, err := r.Regexp.FindStringMatch(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w after %s", ErrMatchTimeout, r.MatchTimeout)
+	}
+	if match == nil {
+		return nil, nil
+	}
+	groups := match.Groups()
+	submatches := make([]string, 0, len(groups))
+	for _, group := range groups {
+		submatches = append(submatches, group.String())
+	}
+	return submatches, nil
+}
+
+func options(flags string, extended bool) (regexp2.RegexOptions, error) {
 	options := regexp2.RegexOptions(regexp2.RE2)
 	for _, flag := range flags {
-		switch flag {
-		case 'i':
+		switch {
+		case flag == 'i':
 			options |= regexp2.IgnoreCase
+		case flag == 'm' && extended:
+			// Let ^ and $ match at line breaks and . match newlines, so
+			// patterns can address individual lines of a commit message.
+			options |= regexp2.Multiline | regexp2.Singleline
+		case flag == 'x' && extended:
+			options |= regexp2.IgnorePatternWhitespace
 		default:
-			return regexp2.None, fmt.Errorf("unsupported regexp flag: %c", flag)
+			return regexp2.None, &FlagError{Flag: flag, Extended: flag == 'm' || flag == 'x'}
 		}
 	}
 
@@ -45,7 +147,7 @@ func options(flags string) (regexp2.RegexOptions, error) {
 }
 
 // Validate rejects regexp features unsupported by the Buildkite server
-// conditional regexp validator.
+// conditional regexp validator. Rejections are reported as *FeatureError.
 func Validate(pattern string) error {
 	escaped := false
 	inClass := false
@@ -100,33 +202,33 @@ func Validate(pattern string) error {
 			}
 			switch {
 			case hasPrefix(pattern, i, "(?<="):
-				return unsupportedFeature("lookbehind")
+				return featureError(i, "lookbehind", "lookbehind `(?<=`", "")
 			case hasPrefix(pattern, i, "(?<!"):
-				return unsupportedFeature("nlookbehind")
+				return featureError(i, "nlookbehind", "negative lookbehind `(?<!`", "")
 			case hasPrefix(pattern, i, "(?>"):
-				return unsupportedFeature("atomic")
+				return featureError(i, "atomic", "atomic group `(?>`", "a non-capturing group `(?:` backtracks and may match differently")
 			case hasPrefix(pattern, i, "(?<"):
-				return unsupportedFeature("named_ab")
+				return namedGroupError(pattern, i, "named_ab", "(?<", '>')
 			case hasPrefix(pattern, i, "(?P<"):
-				return unsupportedFeature("named_ab")
+				return namedGroupError(pattern, i, "named_ab", "(?P<", '>')
 			case hasPrefix(pattern, i, "(?'"):
-				return unsupportedFeature("named_sq")
+				return namedGroupError(pattern, i, "named_sq", "(?'", '\'')
 			case hasPrefix(pattern, i, "(?("):
-				return unsupportedFeature("condition_open")
+				return featureError(i, "condition_open", "conditional group `(?(`", "use alternation with `|` instead")
 			}
 		}
 
 		if i+1 < len(pattern) && pattern[i+1] == '+' {
 			switch ch {
 			case '?':
-				return unsupportedFeature("zero_or_one_possessive")
+				return possessiveError(i, "zero_or_one_possessive", "?")
 			case '*':
-				return unsupportedFeature("zero_or_more_possessive")
+				return possessiveError(i, "zero_or_more_possessive", "*")
 			case '+':
-				return unsupportedFeature("one_or_more_possessive")
+				return possessiveError(i, "one_or_more_possessive", "+")
 			case '}':
-				if isBoundedQuantifierEnd(pattern, i) {
-					return unsupportedFeature("bounded_possessive")
+				if open := boundedQuantifierStart(pattern, i); open != -1 {
+					return possessiveError(open, "bounded_possessive", pattern[open:i+1])
 				}
 			}
 		}
@@ -161,17 +263,19 @@ func commentEnd(pattern string, start int) int {
 	return -1
 }
 
-func isBoundedQuantifierEnd(pattern string, close int) bool {
+// boundedQuantifierStart returns the offset of the '{' that opens the bounded
+// quantifier closed at close, or -1 when close does not end a quantifier.
+func boundedQuantifierStart(pattern string, close int) int {
 	for open := close - 1; open >= 0; open-- {
 		if pattern[open] != '{' {
 			continue
 		}
-		if isEscapedByte(pattern, open) {
-			return false
+		if isEscapedByte(pattern, open) || !isQuantifierBounds(pattern[open+1:close]) {
+			return -1
 		}
-		return isQuantifierBounds(pattern[open+1 : close])
+		return open
 	}
-	return false
+	return -1
 }
 
 func isEscapedByte(pattern string, offset int) bool {
@@ -209,6 +313,48 @@ func hasPrefix(pattern string, offset int, prefix string) bool {
 	return len(pattern)-offset >= len(prefix) && pattern[offset:offset+len(prefix)] == prefix
 }
 
-func unsupportedFeature(feature string) error {
-	return fmt.Errorf("unsupported regexp feature: %s", feature)
+// FeatureError reports a regexp construct that the Buildkite server rejects.
+type FeatureError struct {
+	// Token is the upstream Onigmo token name, such as "nlookbehind".
+	Token string
+	// Name describes the construct, such as "negative lookbehind `(?<!`".
+	Name string
+	// Offset is the byte offset of the construct within the pattern.
+	Offset int
+	// Suggestion describes the closest supported rewrite, when one exists,
+	// and how it differs.
+	Suggestion string
+}
+
+func (e *FeatureError) Error() string {
+	message := fmt.Sprintf("unsupported regexp feature: %s at offset %d", e.Name, e.Offset)
+	if e.Suggestion != "" {
+		message += "; " + e.Suggestion
+	}
+	return message
+}
+
+func featureError(offset int, token, name, suggestion string) *FeatureError {
+	return &FeatureError{Token: token, Name: name, Offset: offset, Suggestion: suggestion}
+}
+
+// namedGroupError reports the named group opened with prefix at offset,
+// suggesting the plain capturing group that replaces it.
+func namedGroupError(pattern string, offset int, token, prefix string, terminator byte) *FeatureError {
+	name := "named group `" + prefix + "`"
+	end := strings.IndexByte(pattern[offset+len(prefix):], terminator)
+	if end == -1 {
+		return featureError(offset, token, name, "use a plain group `(` instead")
+	}
+	group := pattern[offset : offset+len(prefix)+end+1]
+	return featureError(offset, token, name, fmt.Sprintf("use a plain group `(` instead of `%s`", group))
+}
+
+func possessiveError(offset int, token, quantifier string) *FeatureError {
+	return featureError(
+		offset,
+		token,
+		fmt.Sprintf("possessive quantifier `%s+`", quantifier),
+		fmt.Sprintf("the greedy quantifier `%s` backtracks and may match differently", quantifier),
+	)
 }
@@ -1,6 +1,12 @@
 package regex
 
-import "testing"
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestCompileAppliesFlagsAndTimeout(t *testing.T) {
 	compiled, err := Compile(`\[skip tests\]`, "i")
@@ -19,12 +25,81 @@ func TestCompileAppliesFlagsAndTimeout(t *testing.T) {
 	}
 }
 
+func TestCompilerAppliesLimits(t *testing.T) {
+	compiler := Compiler{MatchTimeout: 10 * time.Millisecond, MaxPatternLength: 8}
+
+	if _, err := compiler.Compile(`^(a+)+b$`, ""); err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if _, err := compiler.Compile(`^(a+)+b$|c`, ""); !errors.Is(err, ErrPatternTooLong) {
+		t.Fatalf("Compile error = %v, want ErrPatternTooLong", err)
+	}
+
+	// Case-insensitive patterns always use the backtracking engine.
+	compiled, err := compiler.Compile(`^(a+)+b$`, "i")
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	if compiled.MatchTimeout != compiler.MatchTimeout {
+		t.Fatalf("MatchTimeout = %v, want %v", compiled.MatchTimeout, compiler.MatchTimeout)
+	}
+	if _, err := MatchString(compiled, strings.Repeat("a", 64)); !errors.Is(err, ErrMatchTimeout) {
+		t.Fatalf("MatchString error = %v, want ErrMatchTimeout", err)
+	}
+}
+
+func TestFindStringSubmatch(t *testing.T) {
+	compiled, err := Compile(`^release/(\d+)(\.(\d+))?`, "")
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+
+	got, err := FindStringSubmatch(compiled, "release/12")
+	if err != nil {
+		t.Fatalf("FindStringSubmatch returned error: %v", err)
+	}
+	if want := []string{"release/12", "12", "", ""}; !slices.Equal(got, want) {
+		t.Fatalf("FindStringSubmatch = %q, want %q", got, want)
+	}
+
+	got, err = FindStringSubmatch(compiled, "main")
+	if err != nil {
+		t.Fatalf("FindStringSubmatch returned error: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("FindStringSubmatch = %q, want nil", got)
+	}
+}
+
 func TestCompileRejectsUnsupportedFlags(t *testing.T) {
 	if _, err := Compile("main", "x"); err == nil {
 		t.Fatal("Compile accepted unsupported regexp flag")
 	}
 }
 
+func TestCompilerAcceptsExtendedFlags(t *testing.T) {
+	var flagErr *FlagError
+	if _, err := Compile(`^b$`, "m"); !errors.As(err, &flagErr) || !flagErr.Extended {
+		t.Fatalf("Compile error = %v, want an extended *FlagError", err)
+	}
+
+	compiled, err := Compiler{ExtendedFlags: true}.Compile(`^b $ # line b`, "mx")
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	matched, err := MatchString(compiled, "a\nb\nc")
+	if err != nil {
+		t.Fatalf("MatchString returned error: %v", err)
+	}
+	if !matched {
+		t.Fatal("multiline extended regexp did not match")
+	}
+
+	if _, err := (Compiler{ExtendedFlags: true}).Compile(`a`, "g"); !errors.As(err, &flagErr) || flagErr.Extended {
+		t.Fatalf("Compile error = %v, want a non-extended *FlagError", err)
+	}
+}
+
 func TestValidateRejectsServerUnsupportedFeatures(t *testing.T) {
 	tests := []string{
 		`(?<=a)b`,
@@ -49,6 +124,41 @@ func TestValidateRejectsServerUnsupportedFeatures(t *testing.T) {
 	}
 }
 
+func TestValidateReportsFeaturePositions(t *testing.T) {
+	tests := []struct {
+		pattern        string
+		wantName       string
+		wantOffset     int
+		wantSuggestion string
+	}{
+		{`a(?<!b)c`, "negative lookbehind `(?<!`", 1, ""},
+		{`(?>a*)a`, "atomic group `(?>`", 0, "a non-capturing group `(?:` backtracks and may match differently"},
+		{`^(?<ver>v\d+)$`, "named group `(?<`", 1, "use a plain group `(` instead of `(?<ver>`"},
+		{`(?P<name>x)`, "named group `(?P<`", 0, "use a plain group `(` instead of `(?P<name>`"},
+		{`[(?<=]x(?'id'y)`, "named group `(?'`", 7, "use a plain group `(` instead of `(?'id'`"},
+		{`ab*+`, "possessive quantifier `*+`", 2, "the greedy quantifier `*` backtracks and may match differently"},
+		{`a{1,3}+`, "possessive quantifier `{1,3}+`", 1, "the greedy quantifier `{1,3}` backtracks and may match differently"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			var featureErr *FeatureError
+			if err := Validate(tt.pattern); !errors.As(err, &featureErr) {
+				t.Fatalf("Validate error = %v, want *FeatureError", err)
+			}
+			if featureErr.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", featureErr.Name, tt.wantName)
+			}
+			if featureErr.Offset != tt.wantOffset {
+				t.Errorf("Offset = %d, want %d", featureErr.Offset, tt.wantOffset)
+			}
+			if featureErr.Suggestion != tt.wantSuggestion {
+				t.Errorf("Suggestion = %q, want %q", featureErr.Suggestion, tt.wantSuggestion)
+			}
+		})
+	}
+}
+
 func TestValidateAllowsEscapedUnsupportedSyntax(t *testing.T) {
 	if err := Validate(`\(\?<=a\)`); err != nil {
 		t.Fatalf("Validate returned error: %v", err)
@@ -13,7 +13,15 @@ import (
 
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer) {
+// Start evaluates each line of in against the process environment and writes
+// the results to out. opts configure every evaluation, for example
+// conditional.WithRedaction to keep secrets out of error output.
+func Start(in io.Reader, out io.Writer, opts ...conditional.Option) {
+	evaluator, err := conditional.NewEvaluator(opts...)
+	if err != nil {
+		fmt.Fprintf(out, "ERROR: %s\n", err)
+		return
+	}
 	scanner := bufio.NewScanner(in)
 	ctx := processContext()
 
@@ -29,7 +37,7 @@ func Start(in io.Reader, out io.Writer) {
 			return
 		}
 
-		evaluated, err := conditional.Evaluate(line, ctx)
+		evaluated, err := evaluator.Evaluate(line, ctx)
 		if err != nil {
 			fmt.Fprintf(out, "ERROR: %s\n", err)
 			continue
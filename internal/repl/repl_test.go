@@ -24,7 +24,7 @@ func TestStartPrintsRootErrors(t *testing.T) {
 
 	Start(strings.NewReader("step.key == \"deploy\"\nexit\n"), &out)
 
-	want := ">> ERROR: validation: step variables are not available for entry point \"build_condition\"\n>> "
+	want := ">> ERROR: validation: step variables are not available for entry point \"build_condition\"; `step.key` is only available in build conditions with a step and step notifications\n>> "
 	if out.String() != want {
 		t.Fatalf("Start output = %q, want %q", out.String(), want)
 	}
@@ -98,3 +98,56 @@ func TestContextFromEnvPreservesBuildkiteBuiltins(t *testing.T) {
 		}
 	}
 }
+
+func TestStartRedactsSecretsInErrors(t *testing.T) {
+	t.Setenv("DEPLOY_TOKEN", "s3cr3t-value")
+	t.Setenv("DEPLOY_CREDENTIAL", "hunter2")
+	input := "${BUILDKITE_BRANCH:$DEPLOY_TOKEN} == \"main\"\n${BUILDKITE_BRANCH:$DEPLOY_CREDENTIAL} == \"main\"\nexit\n"
+
+	tests := []struct {
+		name    string
+		options []conditional.Option
+		hidden  []string
+		shown   []string
+	}{
+		{
+			name:    "configured patterns",
+			options: []conditional.Option{conditional.WithRedaction("*_CREDENTIAL")},
+			hidden:  []string{"hunter2"},
+			shown:   []string{"s3cr3t-value"},
+		},
+		{
+			name:  "no redaction by default",
+			shown: []string{"s3cr3t-value", "hunter2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			Start(strings.NewReader(input), &out, tt.options...)
+
+			for _, hidden := range tt.hidden {
+				if strings.Contains(out.String(), hidden) {
+					t.Fatalf("Start output = %q, want %q redacted", out.String(), hidden)
+				}
+			}
+			for _, shown := range tt.shown {
+				if !strings.Contains(out.String(), shown) {
+					t.Fatalf("Start output = %q, want it to contain %q", out.String(), shown)
+				}
+			}
+		})
+	}
+}
+
+func TestStartRejectsInvalidOptions(t *testing.T) {
+	var out bytes.Buffer
+
+	Start(strings.NewReader("true\nexit\n"), &out, conditional.WithRedaction("[*_TOKEN"))
+
+	if !strings.HasPrefix(out.String(), "ERROR: ") || strings.Contains(out.String(), PROMPT) {
+		t.Fatalf("Start output = %q, want only an option error", out.String())
+	}
+}
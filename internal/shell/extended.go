@@ -0,0 +1,360 @@
+package shell
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExtendedEnv is an Env that can enable the POSIX and bash parameter
+// expansions Buildkite does not support: ${#NAME}, ${NAME#pattern},
+// ${NAME##pattern}, ${NAME%pattern}, ${NAME%%pattern},
+// ${NAME/pattern/replacement} with its //, /# and /% forms, and the case
+// conversions ${NAME^}, ${NAME^^}, ${NAME,} and ${NAME,,}. Evaluation rejects
+// them unless the Env implements ExtendedEnv and ExtendedOperators returns
+// true.
+type ExtendedEnv interface {
+	Env
+	ExtendedOperators() bool
+}
+
+// extendedOperators lists the extended operators, longest first so that
+// splitExtendedOperator prefers ## over #.
+var extendedOperators = []string{"##", "#", "%%", "%", "//", "/#", "/%", "/", "^^", "^", ",,", ","}
+
+// Extended reports whether e uses an operator that requires ExtendedEnv.
+func (e Expansion) Extended() bool {
+	if e.Length {
+		return true
+	}
+	for _, operator := range extendedOperators {
+		if e.Operator == operator {
+			return true
+		}
+	}
+	return false
+}
+
+func extendedOperatorsEnabled(env Env) bool {
+	extended, ok := env.(ExtendedEnv)
+	return ok && extended.ExtendedOperators()
+}
+
+func splitExtendedOperator(rest string) (operator string, value string, ok bool) {
+	for _, op := range extendedOperators {
+		if strings.HasPrefix(rest, op) {
+			return op, rest[len(op):], true
+		}
+	}
+	return "", "", false
+}
+
+func unsupportedExtendedOperator(inner string) error {
+	return fmt.Errorf("shell expansion ${%s} is not supported by Buildkite", inner)
+}
+
+// evalLength evaluates ${#NAME}, the number of characters in NAME. An unset
+// variable has length zero.
+func evalLength(inner string, env Env) (string, bool, error) {
+	name, rest, ok := splitName(inner[1:])
+	if !ok || rest != "" {
+		return "", false, fmt.Errorf("invalid shell expansion: ${%s}", inner)
+	}
+	if !extendedOperatorsEnabled(env) {
+		return "", false, unsupportedExtendedOperator(inner)
+	}
+	value, _ := env.LookupEnv(name)
+	return strconv.Itoa(utf8.RuneCountInString(value)), true, nil
+}
+
+func evalExtended(inner string, name string, operator string, operand string, env Env) (string, bool, error) {
+	if !extendedOperatorsEnabled(env) {
+		return "", false, unsupportedExtendedOperator(inner)
+	}
+	base, set := env.LookupEnv(name)
+	if !set {
+		return "", false, nil
+	}
+
+	switch operator {
+	case "^", "^^", ",", ",,":
+		if operand != "" {
+			return "", false, fmt.Errorf("case conversion patterns are not supported: ${%s}", inner)
+		}
+		return convertCase(base, operator), true, nil
+	case "#", "##", "%", "%%":
+		pattern, err := evalGlob(operand, env)
+		if err != nil {
+			return "", false, err
+		}
+		value, err := removeAffix(base, pattern, operator)
+		if err != nil {
+			return "", false, err
+		}
+		return value, true, nil
+	default:
+		rawPattern, rawReplacement := splitReplacement(operand)
+		if rawPattern == "" {
+			return base, true, nil
+		}
+		pattern, err := evalGlob(rawPattern, env)
+		if err != nil {
+			return "", false, err
+		}
+		replacement, err := EvalString(rawReplacement, env)
+		if err != nil {
+			return "", false, err
+		}
+		value, err := replaceGlob(base, pattern, replacement, operator)
+		if err != nil {
+			return "", false, err
+		}
+		return value, true, nil
+	}
+}
+
+func convertCase(value string, operator string) string {
+	convert := strings.ToUpper
+	if operator[0] == ',' {
+		convert = strings.ToLower
+	}
+	if len(operator) == 2 {
+		return convert(value)
+	}
+	first, size := utf8.DecodeRuneInString(value)
+	if first == utf8.RuneError {
+		return value
+	}
+	return convert(string(first)) + value[size:]
+}
+
+// removeAffix removes the shortest (# and %) or longest (## and %%) prefix or
+// suffix of value matching pattern. Suffixes are matched as prefixes of the
+// reversed value so that each form needs a single linear regexp search.
+func removeAffix(value string, pattern glob, operator string) (string, error) {
+	longest := len(operator) == 2
+	if operator[0] == '%' {
+		reversed := reverseString(value)
+		loc, err := pattern.reverse().find(reversed, `^`, longest)
+		if err != nil || loc == nil {
+			return value, err
+		}
+		return value[:len(value)-loc[1]], nil
+	}
+	loc, err := pattern.find(value, `^`, longest)
+	if err != nil || loc == nil {
+		return value, err
+	}
+	return value[loc[1]:], nil
+}
+
+// replaceGlob replaces the longest non-empty match of pattern starting at the
+// leftmost possible position. // replaces every match, /# only a match at the
+// start and /% only a match at the end.
+func replaceGlob(value string, pattern glob, replacement string, operator string) (string, error) {
+	anchor := ""
+	switch operator {
+	case "/#":
+		anchor = `^`
+	case "/%":
+		anchor = `$`
+	}
+	re, err := pattern.compile(anchor, true)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(value, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		out.WriteString(value[last:loc[0]])
+		out.WriteString(replacement)
+		last = loc[1]
+		if operator != "//" {
+			break
+		}
+	}
+	out.WriteString(value[last:])
+	return out.String(), nil
+}
+
+// reverseString reverses value rune by rune, keeping the bytes of invalid
+// UTF-8 sequences so that offsets in the result map back onto value.
+func reverseString(value string) string {
+	out := make([]byte, 0, len(value))
+	for i := len(value); i > 0; {
+		_, size := utf8.DecodeLastRuneInString(value[:i])
+		out = append(out, value[i-size:i]...)
+		i -= size
+	}
+	return string(out)
+}
+
+// splitReplacement splits a ${NAME/pattern/replacement} operand at the first
+// unescaped slash.
+func splitReplacement(operand string) (pattern string, replacement string) {
+	for i := 0; i < len(operand); i++ {
+		switch operand[i] {
+		case '\\':
+			i++
+		case '/':
+			return operand[:i], operand[i+1:]
+		}
+	}
+	return operand, ""
+}
+
+// glob is a compiled shell pattern: one regexp fragment per character, class
+// or star.
+type glob []globToken
+
+type globToken struct {
+	regexp string
+	star   bool
+}
+
+// find returns the location of the first match of g in value with the given
+// anchor, preferring the longest or the shortest match. Every token of a glob
+// other than * matches exactly one character, so with lazy stars the leftmost
+// match that regexp's leftmost-first semantics prefers is also the shortest.
+func (g glob) find(value string, anchor string, longest bool) ([]int, error) {
+	re, err := g.compile(anchor, longest)
+	if err != nil {
+		return nil, err
+	}
+	return re.FindStringIndex(value), nil
+}
+
+// compile translates g into a regexp anchored at the start (^) or end ($) of
+// the value, or unanchored. Longest matches use leftmost-longest semantics;
+// otherwise stars are lazy.
+func (g glob) compile(anchor string, longest bool) (*regexp.Regexp, error) {
+	var out strings.Builder
+	if anchor == `^` {
+		out.WriteString(`^`)
+	}
+	out.WriteString(`(?s:`)
+	for _, token := range g {
+		out.WriteString(token.regexp)
+		if token.star && !longest {
+			out.WriteString(`?`)
+		}
+	}
+	out.WriteString(`)`)
+	if anchor == `$` {
+		out.WriteString(`$`)
+	}
+	re, err := regexp.Compile(out.String())
+	if err != nil {
+		return nil, err
+	}
+	if longest {
+		re.Longest()
+	}
+	return re, nil
+}
+
+// reverse returns the glob matching the reverse of the strings g matches.
+func (g glob) reverse() glob {
+	reversed := make(glob, len(g))
+	for i, token := range g {
+		reversed[len(g)-1-i] = token
+	}
+	return reversed
+}
+
+// evalGlob expands a pattern operand and parses it as a shell glob, where *
+// matches any string, ? any character and [...] a bracket expression. A
+// backslash makes the next character literal.
+func evalGlob(raw string, env Env) (glob, error) {
+	var pattern glob
+	literal := func(value string) {
+		for value != "" {
+			_, size := utf8.DecodeRuneInString(value)
+			pattern = append(pattern, globToken{regexp: regexp.QuoteMeta(value[:size])})
+			value = value[size:]
+		}
+	}
+	for i := 0; i < len(raw); {
+		switch raw[i] {
+		case '$':
+			expansion, next, ok := ReadExpansion(raw, i)
+			if !ok {
+				literal("$")
+				i++
+				continue
+			}
+			value, set, err := EvalRaw(expansion, env)
+			if err != nil {
+				return nil, err
+			}
+			if set {
+				literal(value)
+			}
+			i = next
+		case '\\':
+			if i+1 < len(raw) {
+				i++
+			}
+			_, size := utf8.DecodeRuneInString(raw[i:])
+			literal(raw[i : i+size])
+			i += size
+		case '*':
+			pattern = append(pattern, globToken{regexp: `.*`, star: true})
+			i++
+		case '?':
+			pattern = append(pattern, globToken{regexp: `.`})
+			i++
+		case '[':
+			class, next, ok := globClass(raw, i)
+			if !ok {
+				literal("[")
+				i++
+				continue
+			}
+			pattern = append(pattern, globToken{regexp: class})
+			i = next
+		default:
+			_, size := utf8.DecodeRuneInString(raw[i:])
+			literal(raw[i : i+size])
+			i += size
+		}
+	}
+	return pattern, nil
+}
+
+// globClass translates the bracket expression starting at start into a regexp
+// character class.
+func globClass(raw string, start int) (string, int, bool) {
+	i := start + 1
+	var out strings.Builder
+	out.WriteByte('[')
+	if i < len(raw) && (raw[i] == '!' || raw[i] == '^') {
+		out.WriteByte('^')
+		i++
+	}
+	first := true
+	for i < len(raw) {
+		switch {
+		case raw[i] == ']' && !first:
+			out.WriteByte(']')
+			return out.String(), i + 1, true
+		case raw[i] == '-' && !first && i+1 < len(raw) && raw[i+1] != ']':
+			out.WriteByte('-')
+			i++
+		default:
+			if raw[i] == '\\' && i+1 < len(raw) {
+				i++
+			}
+			_, size := utf8.DecodeRuneInString(raw[i:])
+			out.WriteString(regexp.QuoteMeta(raw[i : i+size]))
+			i += size
+		}
+		first = false
+	}
+	return "", start, false
+}
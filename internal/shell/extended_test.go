@@ -0,0 +1,169 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type extendedEnv struct {
+	env
+}
+
+func (extendedEnv) ExtendedOperators() bool { return true }
+
+func TestEvalStringExtendedOperators(t *testing.T) {
+	vars := extendedEnv{env{
+		"FILE":   "src/pkg/main.go.orig",
+		"BRANCH": "feature/Login-Form",
+		"EMPTY":  "",
+		"WORD":   "héllo",
+		"EXT":    ".go",
+		"SPACED": "a b  c",
+	}}
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: `${#FILE}`, want: "20"},
+		{raw: `${#WORD}`, want: "5"},
+		{raw: `${#UNSET}`, want: "0"},
+		{raw: `${#EMPTY}`, want: "0"},
+		{raw: `${FILE#*/}`, want: "pkg/main.go.orig"},
+		{raw: `${FILE##*/}`, want: "main.go.orig"},
+		{raw: `${FILE%.*}`, want: "src/pkg/main.go"},
+		{raw: `${FILE%%.*}`, want: "src/pkg/main"},
+		{raw: `${FILE#nomatch}`, want: "src/pkg/main.go.orig"},
+		{raw: `${FILE%${EXT}.orig}`, want: "src/pkg/main"},
+		{raw: `${FILE##src/[a-z]?g/}`, want: "main.go.orig"},
+		{raw: `${FILE#[!s]*}`, want: "src/pkg/main.go.orig"},
+		{raw: `${BRANCH/\//-}`, want: "feature-Login-Form"},
+		{raw: `${BRANCH//-/_}`, want: "feature/Login_Form"},
+		{raw: `${BRANCH/#feature\//}`, want: "Login-Form"},
+		{raw: `${BRANCH/%Form/Page}`, want: "feature/Login-Page"},
+		{raw: `${BRANCH/%Login/x}`, want: "feature/Login-Form"},
+		{raw: `${BRANCH/#Login/x}`, want: "feature/Login-Form"},
+		{raw: `${BRANCH/o*/0}`, want: "feature/L0"},
+		{raw: `${SPACED// /}`, want: "abc"},
+		{raw: `${BRANCH//}`, want: "feature/Login-Form"},
+		{raw: `${BRANCH^}`, want: "Feature/Login-Form"},
+		{raw: `${BRANCH^^}`, want: "FEATURE/LOGIN-FORM"},
+		{raw: `${BRANCH,,}`, want: "feature/login-form"},
+		{raw: `${WORD^}`, want: "Héllo"},
+		{raw: `${WORD%l*}`, want: "hél"},
+		{raw: `${WORD%%l*}`, want: "hé"},
+		{raw: `${WORD#*l}`, want: "lo"},
+		{raw: `${WORD/é?/e}`, want: "helo"},
+		{raw: `${WORD/[é]/e}`, want: "hello"},
+		{raw: `${WORD//[!é]/_}`, want: "_é___"},
+		{raw: `${WORD#h[à-ê]}`, want: "llo"},
+		{raw: `${WORD%%[ß\é]*}`, want: "h"},
+		{raw: `${UNSET##*/}`, want: ""},
+		{raw: `${UNSET^^}`, want: ""},
+	}
+
+	for _, tt := range tests {
+		got, err := EvalString(tt.raw, vars)
+		if err != nil {
+			t.Fatalf("EvalString(%q) returned error: %v", tt.raw, err)
+		}
+		if got != tt.want {
+			t.Fatalf("EvalString(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestEvalStringExtendedOperatorsOnLargeValues(t *testing.T) {
+	big := strings.Repeat("a", 64<<10)
+	vars := extendedEnv{env{"BIG": big, "BIGB": big + "b"}}
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: `${BIG//*b/x}`, want: big},
+		{raw: `${BIG/*b/x}`, want: big},
+		{raw: `${BIG/%*b/x}`, want: big},
+		{raw: `${BIG#*b}`, want: big},
+		{raw: `${BIG##*b}`, want: big},
+		{raw: `${BIG%b*}`, want: big},
+		{raw: `${BIG%%b*}`, want: big},
+		{raw: `${BIGB//a/}`, want: "b"},
+		{raw: `${BIGB%a*}`, want: big[1:]},
+		{raw: `${BIGB##*a}`, want: "b"},
+	}
+
+	start := time.Now()
+	for _, tt := range tests {
+		got, err := EvalString(tt.raw, vars)
+		if err != nil {
+			t.Fatalf("EvalString(%q) returned error: %v", tt.raw, err)
+		}
+		if got != tt.want {
+			t.Fatalf("EvalString(%q) returned %d bytes, want %d", tt.raw, len(got), len(tt.want))
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expanding a %d byte value took %s, want linear time", len(big), elapsed)
+	}
+}
+
+func TestEvalRawExtendedOperatorsLeaveUnsetVariablesUnset(t *testing.T) {
+	_, set, err := EvalRaw(`${UNSET%.go}`, extendedEnv{env{}})
+	if err != nil {
+		t.Fatalf("EvalRaw returned error: %v", err)
+	}
+	if set {
+		t.Fatal("EvalRaw returned set=true for an unset variable")
+	}
+}
+
+func TestEvalStringRejectsExtendedOperatorsByDefault(t *testing.T) {
+	for _, raw := range []string{`${#FILE}`, `${FILE##*/}`, `${FILE%.go}`, `${FILE/a/b}`, `${FILE^^}`} {
+		_, err := EvalString(raw, env{"FILE": "main.go"})
+		if err == nil {
+			t.Fatalf("EvalString(%q) did not return an error", raw)
+		}
+		if !strings.Contains(err.Error(), "not supported by Buildkite") {
+			t.Fatalf("EvalString(%q) error = %q, want a Buildkite support error", raw, err)
+		}
+	}
+}
+
+func TestEvalStringRejectsCaseConversionPatterns(t *testing.T) {
+	if _, err := EvalString(`${FILE^^[a-m]}`, extendedEnv{env{"FILE": "main.go"}}); err == nil {
+		t.Fatal("EvalString did not reject a case conversion pattern")
+	}
+}
+
+func TestExpansionsParsesExtendedOperators(t *testing.T) {
+	got := Expansions(`${#NAME} ${FILE##*/} ${REF//$FROM/to} ${BRANCH^^} ${PLAIN:-x}`)
+	want := []Expansion{
+		{Raw: `${#NAME}`, Name: "NAME", Length: true},
+		{Raw: `${FILE##*/}`, Name: "FILE", Operator: "##", Operand: "*/"},
+		{Raw: `${REF//$FROM/to}`, Name: "REF", Operator: "//", Operand: "$FROM/to"},
+		{Raw: `$FROM`, Name: "FROM"},
+		{Raw: `${BRANCH^^}`, Name: "BRANCH", Operator: "^^"},
+		{Raw: `${PLAIN:-x}`, Name: "PLAIN", Operator: ":-", Operand: "x"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expansions = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expansions[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+		if wantExtended := i < 5 && i != 3; got[i].Extended() != wantExtended {
+			t.Fatalf("Expansions[%d].Extended() = %t, want %t", i, got[i].Extended(), wantExtended)
+		}
+	}
+}
+
+func TestContainsExpansionRecognisesExtendedOperators(t *testing.T) {
+	for _, value := range []string{`${#NAME}`, `x${FILE%%.*}y`, `${REF/a/b}`, `${BRANCH,}`} {
+		if !ContainsExpansion(value) {
+			t.Fatalf("ContainsExpansion(%q) = false, want true", value)
+		}
+	}
+}
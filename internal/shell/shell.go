@@ -54,6 +54,140 @@ func ContainsExpansion(value string) bool {
 	return false
 }
 
+// Expansion is a shell expansion found by Expansions.
+type Expansion struct {
+	// Raw is the expansion source, such as ${NAME:-fallback}.
+	Raw  string
+	Name string
+	// Length is true for ${#NAME}.
+	Length bool
+	// Operator is "-", ":-", "+", ":+", "?" or ":?" for parameter
+	// operators, ":" for a substring, one of the ExtendedEnv operators, or ""
+	// for a plain expansion.
+	Operator string
+	// Operand is the source after the operator.
+	Operand string
+}
+
+// Expansions returns the shell expansions in value in source order, including
+// expansions nested in operands such as $B in ${A:-$B}. $$ escapes and
+// backslash escapes are skipped.
+func Expansions(value string) []Expansion {
+	var expansions []Expansion
+	for i := 0; i < len(value); {
+		switch value[i] {
+		case '$':
+			if i+1 < len(value) && value[i+1] == '$' {
+				i += 2
+				continue
+			}
+			raw, next, ok := ReadExpansion(value, i)
+			if !ok {
+				i++
+				continue
+			}
+			if expansion, ok := parseExpansion(raw); ok {
+				expansions = append(expansions, expansion)
+				expansions = append(expansions, Expansions(expansion.Operand)...)
+			}
+			i = next
+		case '\\':
+			_, next, err := ReadStringEscape(value, i)
+			if err != nil {
+				i++
+				continue
+			}
+			i = next
+		default:
+			i++
+		}
+	}
+	return expansions
+}
+
+func parseExpansion(raw string) (Expansion, bool) {
+	inner := raw[1:]
+	braced := strings.HasPrefix(inner, "{") && strings.HasSuffix(inner, "}")
+	length := false
+	if braced {
+		inner = inner[1 : len(inner)-1]
+		length = strings.HasPrefix(inner, "#")
+		if length {
+			inner = inner[1:]
+		}
+	}
+	name, rest, ok := splitName(inner)
+	if !ok || length && rest != "" {
+		return Expansion{}, false
+	}
+	expansion := Expansion{Raw: raw, Name: name, Length: length}
+	if !braced || rest == "" {
+		return expansion, true
+	}
+
+	if operator, operand, ok := splitOperator(rest); ok {
+		expansion.Operator, expansion.Operand = operator, operand
+	} else if strings.HasPrefix(rest, ":") {
+		expansion.Operator, expansion.Operand = ":", rest[1:]
+	} else if operator, operand, ok := splitExtendedOperator(rest); ok {
+		expansion.Operator, expansion.Operand = operator, operand
+	} else {
+		expansion.Operand = rest
+	}
+	return expansion, true
+}
+
+// NonIntegerOffsets returns the literal offset and length operands of a
+// substring expansion that are not integers. Evaluating the expansion fails
+// on them whenever the variable is set.
+func (e Expansion) NonIntegerOffsets() []string {
+	if e.Operator != ":" {
+		return nil
+	}
+	var invalid []string
+	for _, part := range splitTopLevel(e.Operand, ':') {
+		if ContainsExpansion(part) {
+			continue
+		}
+		value, err := EvalString(part, emptyEnv{})
+		if err != nil || !integerPattern.MatchString(strings.TrimSpace(value)) {
+			invalid = append(invalid, part)
+		}
+	}
+	return invalid
+}
+
+// Default returns the fallback of a "-" or ":-" expansion when it contains no
+// expansions of its own, so its value is known statically.
+func (e Expansion) Default() (string, bool) {
+	if e.Operator != "-" && e.Operator != ":-" || ContainsExpansion(e.Operand) {
+		return "", false
+	}
+	value, err := EvalString(e.Operand, emptyEnv{})
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+type emptyEnv struct{}
+
+func (emptyEnv) LookupEnv(string) (string, bool) { return "", false }
+
+// References returns the names of the environment variables that shell
+// expansions in value read, in order of first use.
+func References(value string) []string {
+	var names []string
+	seen := map[string]struct{}{}
+	for _, expansion := range Expansions(value) {
+		if _, ok := seen[expansion.Name]; !ok {
+			seen[expansion.Name] = struct{}{}
+			names = append(names, expansion.Name)
+		}
+	}
+	return names
+}
+
 // EvalRaw evaluates a standalone shell expansion.
 func EvalRaw(raw string, env Env) (string, bool, error) {
 	if strings.HasPrefix(raw, "${") && strings.HasSuffix(raw, "}") {
@@ -77,6 +211,9 @@ func EvalRaw(raw string, env Env) (string, bool, error) {
 }
 
 func evalBraced(inner string, env Env) (string, bool, error) {
+	if strings.HasPrefix(inner, "#") {
+		return evalLength(inner, env)
+	}
 	name, rest, ok := splitName(inner)
 	if !ok {
 		return "", false, fmt.Errorf("invalid shell expansion: ${%s}", inner)
@@ -93,6 +230,9 @@ func evalBraced(inner string, env Env) (string, bool, error) {
 
 	operator, valueRaw, ok := splitOperator(rest)
 	if !ok {
+		if operator, operand, ok := splitExtendedOperator(rest); ok {
+			return evalExtended(inner, name, operator, operand, env)
+		}
 		return "", false, fmt.Errorf("invalid shell expansion: ${%s}", inner)
 	}
 
@@ -1,6 +1,9 @@
 package shell
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 type env map[string]string
 
@@ -52,3 +55,83 @@ func TestReadExpansionIncludesNestedFallback(t *testing.T) {
 		t.Fatalf("ReadExpansion next = %d, want %d", next, len(got))
 	}
 }
+
+func TestReferencesListsExpandedNames(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: `deploy to $ENV`, want: []string{"ENV"}},
+		{value: `${A:-$B} and $A.suffix`, want: []string{"A", "B"}},
+		{value: `${NAME:0:${LEN}}`, want: []string{"NAME", "LEN"}},
+		{value: `costs $5 or $$HOME or \$HOME`, want: nil},
+	}
+
+	for _, tt := range tests {
+		got := References(tt.value)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Fatalf("References(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestExpansionsParsesOperators(t *testing.T) {
+	got := Expansions(`deploy ${ENV:-$DEFAULT} ${NAME:0:3} ${TOKEN:?} $$SKIP`)
+	want := []Expansion{
+		{Raw: `${ENV:-$DEFAULT}`, Name: "ENV", Operator: ":-", Operand: "$DEFAULT"},
+		{Raw: `$DEFAULT`, Name: "DEFAULT"},
+		{Raw: `${NAME:0:3}`, Name: "NAME", Operator: ":", Operand: "0:3"},
+		{Raw: `${TOKEN:?}`, Name: "TOKEN", Operator: ":?"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expansions = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expansions[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExpansionNonIntegerOffsets(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: `${NAME:0:3}`},
+		{raw: `${NAME: -2}`},
+		{raw: `${NAME:"1"}`},
+		{raw: `${NAME:$START:3}`},
+		{raw: `${NAME:a:3}`, want: []string{"a"}},
+		{raw: `${NAME:1:1.5}`, want: []string{"1.5"}},
+		{raw: `${NAME:-a}`},
+	}
+
+	for _, tt := range tests {
+		got := Expansions(tt.raw)[0].NonIntegerOffsets()
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Fatalf("NonIntegerOffsets(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestExpansionDefault(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{raw: `${STATE:-passed}`, want: "passed", wantOK: true},
+		{raw: `${STATE-'soft failed'}`, want: "soft failed", wantOK: true},
+		{raw: `${STATE:-$OTHER}`},
+		{raw: `${STATE:+passed}`},
+		{raw: `$STATE`},
+	}
+
+	for _, tt := range tests {
+		got, ok := Expansions(tt.raw)[0].Default()
+		if got != tt.want || ok != tt.wantOK {
+			t.Fatalf("Default(%q) = %q, %t, want %q, %t", tt.raw, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -23,6 +23,15 @@ const (
 	RE_NOT_EQ = "!~"
 	INCLUDES  = "includes"
 
+	// Ordering Operators, only produced when the lexer enables them
+	LT    = "<"
+	LT_EQ = "<="
+	GT    = ">"
+	GT_EQ = ">="
+
+	// Membership Operator, only produced when the lexer enables it
+	IN = "in"
+
 	// Boolean Operators
 	AND = "&&"
 	OR  = "||"
@@ -47,6 +56,8 @@ type Token struct {
 	Literal string
 	Flags   string
 	Raw     string
+	Pos     int // byte offset of the token's first character
+	End     int // byte offset just past the token's last character
 }
 
 var keywords = map[string]TokenType{
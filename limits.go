@@ -0,0 +1,124 @@
+package conditional
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/buildkite/conditional/internal/ast"
+	"github.com/buildkite/conditional/internal/parser"
+	"github.com/buildkite/conditional/internal/regex"
+)
+
+// limits bounds the resources a single expression may use. Zero fields are
+// unlimited, except matchTimeout which falls back to regex.MatchTimeout.
+type limits struct {
+	matchTimeout        time.Duration
+	maxPatternLength    int
+	maxExpressionLength int
+	maxDepth            int
+	maxArrayLength      int
+}
+
+// WithRegexTimeout bounds each regular expression match. Matches that run
+// longer fail evaluation with ErrorKindTimeout. The default is one second.
+func WithRegexTimeout(timeout time.Duration) Option {
+	return func(options *optionSet) error {
+		if timeout <= 0 {
+			return validationError("regex timeout must be positive, got %s", timeout)
+		}
+		options.limits.matchTimeout = timeout
+		return nil
+	}
+}
+
+// WithMaxRegexLength rejects regular expression literals whose pattern is
+// longer than n bytes.
+func WithMaxRegexLength(n int) Option {
+	return positiveLimit("maximum regex length", n, func(limits *limits) { limits.maxPatternLength = n })
+}
+
+// WithMaxExpressionLength rejects expressions longer than n bytes before they
+// are parsed.
+func WithMaxExpressionLength(n int) Option {
+	return positiveLimit("maximum expression length", n, func(limits *limits) { limits.maxExpressionLength = n })
+}
+
+// WithMaxDepth rejects expressions whose syntax tree is nested more than n
+// levels deep. A lone literal or variable has depth 1, and each parenthesised
+// group also counts as a level. Parsing stops as soon as the limit is passed.
+func WithMaxDepth(n int) Option {
+	return positiveLimit("maximum depth", n, func(limits *limits) { limits.maxDepth = n })
+}
+
+// WithMaxArrayLength rejects array literals with more than n elements.
+func WithMaxArrayLength(n int) Option {
+	return positiveLimit("maximum array length", n, func(limits *limits) { limits.maxArrayLength = n })
+}
+
+func positiveLimit(name string, n int, set func(*limits)) Option {
+	return func(options *optionSet) error {
+		if n <= 0 {
+			return validationError("%s must be positive, got %d", name, n)
+		}
+		set(&options.limits)
+		return nil
+	}
+}
+
+func (o optionSet) parserOptions() []parser.Option {
+	return []parser.Option{
+		parser.WithRegexCompiler(o.regexCompiler()),
+		parser.WithMaxDepth(o.limits.maxDepth),
+	}
+}
+
+func limitError(format string, args ...any) *Error {
+	return &Error{Kind: ErrorKindLimit, Message: fmt.Sprintf(format, args...)}
+}
+
+func (o optionSet) checkExpressionLength(expression string) error {
+	if max := o.limits.maxExpressionLength; max > 0 && len(expression) > max {
+		return limitError("expression is %d bytes, over the limit of %d", len(expression), max)
+	}
+	return nil
+}
+
+// checkParseLimits reports a limit error when a parse failure was caused by a
+// regular expression over the pattern length limit or by nesting past the depth
+// limit.
+func checkParseLimits(errs []error) error {
+	for _, err := range errs {
+		var depthErr *parser.DepthError
+		if errors.Is(err, regex.ErrPatternTooLong) || errors.As(err, &depthErr) {
+			return &Error{Kind: ErrorKindLimit, Message: err.Error(), Cause: err}
+		}
+	}
+	return nil
+}
+
+// checkTreeLimits enforces the depth and array length limits on a parsed
+// expression.
+func (o optionSet) checkTreeLimits(expr ast.Expression) error {
+	if o.limits.maxDepth == 0 && o.limits.maxArrayLength == 0 {
+		return nil
+	}
+	return o.checkNode(expr, 1)
+}
+
+func (o optionSet) checkNode(expr ast.Expression, depth int) error {
+	if max := o.limits.maxDepth; max > 0 && depth > max {
+		return limitError("expression is nested more than %d levels deep", max)
+	}
+	if array, ok := expr.(*ast.ArrayLiteral); ok {
+		if max := o.limits.maxArrayLength; max > 0 && len(array.Elements) > max {
+			return limitError("array literal has %d elements, over the limit of %d", len(array.Elements), max)
+		}
+	}
+	for _, child := range ast.Children(expr) {
+		if err := o.checkNode(child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package conditional
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/buildkite/conditional/internal/regex"
+)
+
+func TestRegexTimeoutOption(t *testing.T) {
+	evaluator, err := NewEvaluator(WithRegexTimeout(10 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	ctx := Context{Build: Build{Message: str(strings.Repeat("a", 64))}}
+	// Case-insensitive patterns always use the backtracking engine.
+	expression := `build.message =~ /^(a+)+b$/i`
+
+	_, err = evaluator.Evaluate(expression, ctx)
+	if !IsErrorKind(err, ErrorKindTimeout) {
+		t.Fatalf("Evaluate(%q) error = %v, want %s", expression, err, ErrorKindTimeout)
+	}
+	if !errors.Is(err, regex.ErrMatchTimeout) {
+		t.Fatalf("Evaluate(%q) error = %v, want it to wrap regex.ErrMatchTimeout", expression, err)
+	}
+}
+
+func TestResourceLimitOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		option     Option
+		expression string
+		wantError  ErrorKind
+	}{
+		{
+			name:       "expression within length limit",
+			option:     WithMaxExpressionLength(32),
+			expression: `build.branch == "main"`,
+		},
+		{
+			name:       "expression over length limit",
+			option:     WithMaxExpressionLength(8),
+			expression: `build.branch == "main"`,
+			wantError:  ErrorKindLimit,
+		},
+		{
+			name:       "regex within length limit",
+			option:     WithMaxRegexLength(8),
+			expression: `build.branch =~ /^main$/`,
+		},
+		{
+			name:       "regex over length limit",
+			option:     WithMaxRegexLength(4),
+			expression: `build.branch =~ /^main$/`,
+			wantError:  ErrorKindLimit,
+		},
+		{
+			name:       "depth within limit",
+			option:     WithMaxDepth(3),
+			expression: `build.branch == "main" && build.tag == null`,
+		},
+		{
+			name:       "depth over limit",
+			option:     WithMaxDepth(3),
+			expression: `build.branch == "main" && (build.tag == null || build.tag == "v1")`,
+			wantError:  ErrorKindLimit,
+		},
+		{
+			name:       "array within length limit",
+			option:     WithMaxArrayLength(2),
+			expression: `["main", "next"] includes build.branch`,
+		},
+		{
+			name:       "array over length limit",
+			option:     WithMaxArrayLength(2),
+			expression: `["main", "next", "release"] includes build.branch`,
+			wantError:  ErrorKindLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.expression, Context{}, tt.option)
+			if tt.wantError != "" {
+				if !IsErrorKind(err, tt.wantError) {
+					t.Fatalf("Validate(%q) error = %v, want %s", tt.expression, err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate(%q) returned error: %v", tt.expression, err)
+			}
+		})
+	}
+}
+
+func TestMaxDepthStopsParsingDeeplyNestedExpressions(t *testing.T) {
+	for _, expression := range []string{
+		strings.Repeat("(", 100000) + `build.branch == "main"` + strings.Repeat(")", 100000),
+		strings.Repeat("!", 100000) + `build.pull_request.draft`,
+	} {
+		err := Validate(expression, Context{}, WithMaxDepth(32))
+		if !IsErrorKind(err, ErrorKindLimit) {
+			t.Fatalf("Validate error = %v, want %s", err, ErrorKindLimit)
+		}
+		if want := "expression is nested more than 32 levels deep"; !strings.Contains(err.Error(), want) {
+			t.Fatalf("Validate error = %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestResourceLimitOptionsRejectNonPositiveValues(t *testing.T) {
+	for name, option := range map[string]Option{
+		"regex timeout":     WithRegexTimeout(0),
+		"regex length":      WithMaxRegexLength(0),
+		"expression length": WithMaxExpressionLength(-1),
+		"depth":             WithMaxDepth(0),
+		"array length":      WithMaxArrayLength(0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewEvaluator(option); !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+			}
+		})
+	}
+}
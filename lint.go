@@ -0,0 +1,281 @@
+package conditional
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buildkite/conditional/internal/ast"
+)
+
+// Severity classifies a Diagnostic.
+type Severity string
+
+const (
+	// SeverityError reports an expression that fails parsing or validation.
+	SeverityError Severity = "error"
+	// SeverityWarning reports a valid expression that is probably a mistake.
+	SeverityWarning Severity = "warning"
+	// SeverityInfo reports behavior that is valid but easy to misread.
+	SeverityInfo Severity = "info"
+)
+
+// Diagnostic is a lint finding for a conditional expression.
+type Diagnostic struct {
+	Severity Severity
+	// Code is a stable identifier for the kind of finding, such as
+	// "always-false".
+	Code    string
+	Message string
+	// Expression is the formatted sub-expression the finding refers to.
+	Expression string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+}
+
+const (
+	diagnosticInvalid           = "invalid"
+	diagnosticAlwaysTrue        = "always-true"
+	diagnosticAlwaysFalse       = "always-false"
+	diagnosticSelfComparison    = "self-comparison"
+	diagnosticDoubleNegation    = "double-negation"
+	diagnosticIdenticalBranches = "identical-branches"
+	diagnosticDeadBranch        = "dead-branch"
+	diagnosticNullable          = "nullable"
+	diagnosticStringTemplate    = "string-template"
+	diagnosticUnsupportedEnv    = "unsupported-env"
+	diagnosticSubstringOffset   = "substring-offset"
+	diagnosticRequiredExpansion = "required-expansion"
+	diagnosticInvalidDefault    = "invalid-default"
+)
+
+// Lint reports likely mistakes in expression for the selected Buildkite
+// context. Findings are warnings, not errors: the expression still validates
+// and evaluates. If expression fails parsing or validation, Lint returns a
+// single SeverityError diagnostic describing the failure.
+func Lint(expression string, ctx Context, opts ...Option) []Diagnostic {
+	options, err := applyOptions(opts)
+	if err != nil {
+		return []Diagnostic{errorDiagnostic(err)}
+	}
+	return lint(expression, ctx, options)
+}
+
+// Lint reports likely mistakes in expression using the evaluator's options.
+func (e Evaluator) Lint(expression string, ctx Context) []Diagnostic {
+	return lint(expression, ctx, e.options)
+}
+
+func lint(expression string, ctx Context, options optionSet) []Diagnostic {
+	entryPoint, err := normalizeEntryPoint(ctx.EntryPoint)
+	if err != nil {
+		return []Diagnostic{errorDiagnostic(err)}
+	}
+	if strings.TrimSpace(expression) == "" {
+		return nil
+	}
+
+	expr, err := parse(expression, options)
+	if err != nil {
+		return []Diagnostic{errorDiagnostic(err)}
+	}
+	ctx.EntryPoint = entryPoint
+	if err := validateExpression(expr, ctx, options); err != nil {
+		return []Diagnostic{errorDiagnostic(err)}
+	}
+
+	l := linter{
+		checker:     newTypeChecker(ctx, options),
+		nullable:    nullableVariables(ctx),
+		nullChecked: map[string]struct{}{},
+	}
+	l.collectNullChecks(expr)
+	l.lint(expr)
+	l.lintExpansions(expr)
+	for _, literal := range runtimeStringLiterals(expr) {
+		l.diagnostics = append(l.diagnostics, Diagnostic{
+			Severity:   SeverityInfo,
+			Code:       diagnosticStringTemplate,
+			Message:    stringTemplateMessage(literal),
+			Expression: literal.String(),
+		})
+	}
+	return l.diagnostics
+}
+
+func errorDiagnostic(err error) Diagnostic {
+	message := err.Error()
+	if conditionalErr, ok := err.(*Error); ok && conditionalErr.Message != "" {
+		message = conditionalErr.Message
+	}
+	return Diagnostic{Severity: SeverityError, Code: diagnosticInvalid, Message: message}
+}
+
+type linter struct {
+	checker typeChecker
+	// nullable maps nullable variables to when they are null.
+	nullable map[string]string
+	// nullChecked holds variables the expression compares with null, which
+	// shows the author has already considered the null case.
+	nullChecked map[string]struct{}
+	diagnostics []Diagnostic
+}
+
+func (l *linter) warn(code string, expr ast.Expression, format string, args ...any) {
+	l.warnMessage(code, expr, fmt.Sprintf(format, args...))
+}
+
+func (l *linter) warnMessage(code string, expr ast.Expression, message string) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity:   SeverityWarning,
+		Code:       code,
+		Message:    message,
+		Expression: expr.String(),
+	})
+}
+
+func (l *linter) lint(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.PrefixExpression:
+		if inner, ok := expr.Right.(*ast.PrefixExpression); ok && expr.Operator == "!" && inner.Operator == "!" {
+			l.warn(diagnosticDoubleNegation, expr, "double negation `%s` can be written as `%s`", expr, inner.Right)
+			l.lint(inner.Right)
+			return
+		}
+		if l.lintConstant(expr) {
+			return
+		}
+		if variable, when, ok := l.nullableVariable(expr.Right); ok && expr.Operator == "!" {
+			l.warn(diagnosticNullable, expr, "`%s` is null %s, so `%s` is also true when it is null", variable, when, expr)
+		}
+		l.lint(expr.Right)
+	case *ast.InfixExpression:
+		switch expr.Operator {
+		case "&&", "||":
+			if l.lintConstant(expr) {
+				return
+			}
+		case "==", "!=":
+			if sameExpression(expr.Left, expr.Right) {
+				result := "true"
+				if expr.Operator == "!=" {
+					result = "false"
+				}
+				l.warn(diagnosticSelfComparison, expr, "`%s` is compared with itself, so `%s` is always %s", expr.Left, expr, result)
+				return
+			}
+			l.lintNullableComparison(expr)
+			l.lintExpansionDefault(expr)
+		}
+		l.lint(expr.Left)
+		l.lint(expr.Right)
+	case *ast.ConditionalExpression:
+		if sameExpression(expr.Consequence, expr.Alternative) {
+			l.warn(diagnosticIdenticalBranches, expr, "both branches of `%s` are `%s`, so the condition has no effect", expr, expr.Consequence)
+		}
+		if result, ok := constantCondition(expr.Condition, l.checker); ok {
+			dead := expr.Alternative
+			if !result {
+				dead = expr.Consequence
+			}
+			l.warn(diagnosticDeadBranch, expr, "`%s` is always %t, so `%s` is never used", expr.Condition, result, dead)
+		} else {
+			l.lint(expr.Condition)
+		}
+		l.lint(expr.Consequence)
+		l.lint(expr.Alternative)
+	case *ast.CallExpression:
+		for _, arg := range expr.Arguments {
+			l.lint(arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range expr.Elements {
+			l.lint(element)
+		}
+	}
+}
+
+// lintConstant warns when a logical expression has the same result for every
+// possible value of the operands it compares.
+func (l *linter) lintConstant(expr ast.Expression) bool {
+	result, ok := constantCondition(expr, l.checker)
+	if !ok {
+		return false
+	}
+	if result {
+		l.warn(diagnosticAlwaysTrue, expr, "`%s` is always true", expr)
+	} else {
+		l.warn(diagnosticAlwaysFalse, expr, "`%s` is always false", expr)
+	}
+	return true
+}
+
+// lintNullableComparison warns about comparisons whose result for a null
+// variable is easy to misread: `x != "a"` is true when x is null, and
+// `x == false` is false.
+func (l *linter) lintNullableComparison(expr *ast.InfixExpression) {
+	subject, other := expr.Left, expr.Right
+	if _, ok := literalCandidate(subject); ok {
+		subject, other = other, subject
+	}
+	variable, when, ok := l.nullableVariable(subject)
+	if !ok {
+		return
+	}
+
+	switch expr.Operator {
+	case "!=":
+		if literal, ok := literalCandidate(other); !ok || literal == nullCandidate {
+			return
+		}
+		l.warn(diagnosticNullable, expr, "`%s` is null %s, so `%s` is also true when it is null", variable, when, expr)
+	case "==":
+		if boolean, ok := other.(*ast.Boolean); ok && !boolean.Value {
+			l.warn(diagnosticNullable, expr, "`%s` is null %s, so `%s` is false when it is null; use `%s != true` to include those builds", variable, when, expr, variable)
+		}
+	}
+}
+
+// nullableVariable returns the name of a nullable variable and when it is
+// null, unless the expression already compares it with null.
+func (l *linter) nullableVariable(expr ast.Expression) (string, string, bool) {
+	identifier, ok := expr.(*ast.Identifier)
+	if !ok {
+		return "", "", false
+	}
+	when, ok := l.nullable[identifier.Value]
+	if !ok {
+		return "", "", false
+	}
+	if _, checked := l.nullChecked[identifier.Value]; checked {
+		return "", "", false
+	}
+	return identifier.Value, when, true
+}
+
+func (l *linter) collectNullChecks(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.PrefixExpression:
+		l.collectNullChecks(expr.Right)
+	case *ast.InfixExpression:
+		if expr.Operator == "==" || expr.Operator == "!=" {
+			if _, ok := expr.Right.(*ast.Null); ok {
+				l.nullChecked[identifierName(expr.Left)] = struct{}{}
+			}
+			if _, ok := expr.Left.(*ast.Null); ok {
+				l.nullChecked[identifierName(expr.Right)] = struct{}{}
+			}
+		}
+		l.collectNullChecks(expr.Left)
+		l.collectNullChecks(expr.Right)
+	case *ast.ConditionalExpression:
+		l.collectNullChecks(expr.Condition)
+		l.collectNullChecks(expr.Consequence)
+		l.collectNullChecks(expr.Alternative)
+	}
+}
+
+func sameExpression(left, right ast.Expression) bool {
+	return left.String() == right.String()
+}
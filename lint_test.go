@@ -0,0 +1,318 @@
+package conditional
+
+import (
+	"strings"
+	"testing"
+)
+
+type lintCase struct {
+	name                string
+	expression          string
+	ctx                 Context
+	opts                []Option
+	wantCodes           []string
+	wantMessageContains string
+}
+
+func runLintCases(t *testing.T, tests []lintCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := Lint(tt.expression, tt.ctx, tt.opts...)
+			codes := make([]string, 0, len(diagnostics))
+			for _, diagnostic := range diagnostics {
+				codes = append(codes, diagnostic.Code)
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.wantCodes, ",") {
+				t.Fatalf("Lint(%q) codes = %v, want %v (%v)", tt.expression, codes, tt.wantCodes, diagnostics)
+			}
+			if tt.wantMessageContains == "" {
+				return
+			}
+			for _, diagnostic := range diagnostics {
+				if strings.Contains(diagnostic.Message, tt.wantMessageContains) {
+					return
+				}
+			}
+			t.Fatalf("Lint(%q) = %v, want message containing %q", tt.expression, diagnostics, tt.wantMessageContains)
+		})
+	}
+}
+
+func TestLintContradictionsAndTautologies(t *testing.T) {
+	runLintCases(t, []lintCase{
+		{
+			name:       "different branches",
+			expression: `build.branch == "main" && build.branch == "dev"`,
+			wantCodes:  []string{diagnosticAlwaysFalse},
+		},
+		{
+			name:       "null and not null",
+			expression: `build.tag == null && build.tag != null`,
+			wantCodes:  []string{diagnosticAlwaysFalse},
+		},
+		{
+			name:       "not equal to either value",
+			expression: `step.outcome != "passed" || step.outcome != "soft_failed"`,
+			ctx:        Context{EntryPoint: EntryPointStepNotification},
+			wantCodes:  []string{diagnosticAlwaysTrue},
+		},
+		{
+			name:       "term or its negation",
+			expression: `build.pull_request.draft || !build.pull_request.draft`,
+			wantCodes:  []string{diagnosticAlwaysTrue},
+		},
+		{
+			name:       "nested contradiction",
+			expression: `build.tag != null || (build.branch == "main" && build.branch == "dev")`,
+			wantCodes:  []string{diagnosticAlwaysFalse},
+		},
+		{
+			name:       "shell expansion contradiction",
+			expression: `$DEPLOY == "yes" && $DEPLOY == "no"`,
+			wantCodes:  []string{diagnosticAlwaysFalse},
+		},
+		{
+			name:       "number literals are distinct from strings",
+			expression: `build.number == 1 && build.number != 2`,
+		},
+		{
+			name:       "alternatives are fine",
+			expression: `build.branch == "main" || build.branch == "dev"`,
+		},
+		{
+			name:       "different subjects are fine",
+			expression: `build.branch == "main" && pipeline.default_branch == "dev"`,
+		},
+	})
+}
+
+func TestLintUsesEnumerationMembers(t *testing.T) {
+	environment := WithVariable("deploy.environment", EnumType("deploy environment", "staging", "production"), func(Context) Value {
+		return StringValue("staging")
+	})
+
+	runLintCases(t, []lintCase{
+		{
+			name:       "different sources",
+			expression: `build.source == "webhook" && build.source == "ui"`,
+			wantCodes:  []string{diagnosticAlwaysFalse},
+		},
+		{
+			name:       "every member or null",
+			expression: `build.blocked_state == "failed" || build.blocked_state == "passed" || build.blocked_state == "running" || build.blocked_state == null`,
+			wantCodes:  []string{diagnosticAlwaysTrue},
+		},
+		{
+			name:       "no member and not null",
+			expression: `build.blocked_state != "failed" && build.blocked_state != "passed" && build.blocked_state != "running" && build.blocked_state != null`,
+			wantCodes:  []string{diagnosticAlwaysFalse},
+		},
+		{
+			name:       "every member but null may be unset",
+			expression: `build.blocked_state == "failed" || build.blocked_state == "passed" || build.blocked_state == "running"`,
+		},
+		{
+			name:       "strings are open ended",
+			expression: `build.branch == "main" || build.branch == "dev" || build.branch == null`,
+		},
+		{
+			name:       "custom enumeration",
+			expression: `deploy.environment == "staging" || deploy.environment == "production" || deploy.environment == null`,
+			opts:       []Option{environment},
+			wantCodes:  []string{diagnosticAlwaysTrue},
+		},
+		{
+			name:       "dead ternary branch",
+			expression: `(step.outcome == "passed" && step.outcome == "errored") ? build.tag != null : build.branch == "main"`,
+			ctx:        Context{EntryPoint: EntryPointStepNotification},
+			wantCodes:  []string{diagnosticDeadBranch},
+		},
+	})
+}
+
+func TestLintSuspiciousForms(t *testing.T) {
+	runLintCases(t, []lintCase{
+		{
+			name:                "self comparison",
+			expression:          `build.branch == build.branch`,
+			wantCodes:           []string{diagnosticSelfComparison},
+			wantMessageContains: "always true",
+		},
+		{
+			name:                "self inequality",
+			expression:          `env("FOO") != env("FOO")`,
+			wantCodes:           []string{diagnosticSelfComparison},
+			wantMessageContains: "always false",
+		},
+		{
+			name:                "double negation",
+			expression:          `!!build.pull_request.draft`,
+			wantCodes:           []string{diagnosticDoubleNegation},
+			wantMessageContains: "can be written as `build.pull_request.draft`",
+		},
+		{
+			name:       "identical ternary branches",
+			expression: `build.branch == "main" ? build.tag != null : build.tag != null`,
+			wantCodes:  []string{diagnosticIdenticalBranches},
+		},
+		{
+			name:       "clean expression",
+			expression: `build.branch == "main" && build.message !~ /\[skip ci\]/`,
+		},
+	})
+}
+
+func TestLintNullableVariables(t *testing.T) {
+	runLintCases(t, []lintCase{
+		{
+			name:                "not equal to true outside pull requests",
+			expression:          `build.pull_request.draft != true`,
+			wantCodes:           []string{diagnosticNullable},
+			wantMessageContains: "is null for builds that are not pull requests",
+		},
+		{
+			name:                "not equal to a string",
+			expression:          `build.pull_request.base_branch != "main"`,
+			wantCodes:           []string{diagnosticNullable},
+			wantMessageContains: "is also true when it is null",
+		},
+		{
+			name:                "reversed operands",
+			expression:          `"main" != build.pull_request.base_branch`,
+			wantCodes:           []string{diagnosticNullable},
+			wantMessageContains: "is also true when it is null",
+		},
+		{
+			name:                "equal to false",
+			expression:          `build.pull_request.draft == false`,
+			wantCodes:           []string{diagnosticNullable},
+			wantMessageContains: "use `build.pull_request.draft != true` to include those builds",
+		},
+		{
+			name:                "negated nullable boolean",
+			expression:          `!build.pull_request.repository.fork`,
+			wantCodes:           []string{diagnosticNullable},
+			wantMessageContains: "is also true when it is null",
+		},
+		{
+			name:                "step outcome before the step finishes",
+			expression:          `step.outcome != "passed"`,
+			ctx:                 Context{EntryPoint: EntryPointStepNotification},
+			wantCodes:           []string{diagnosticNullable},
+			wantMessageContains: "is null until the step finishes",
+		},
+		{
+			name:       "null already considered",
+			expression: `build.pull_request.id != null && build.pull_request.draft != true && build.pull_request.draft != null`,
+		},
+		{
+			name:       "equal to a string is not reported",
+			expression: `build.pull_request.base_branch == "main"`,
+		},
+		{
+			name:       "variables that are always set are not reported",
+			expression: `build.branch != "main" && build.state != "passed"`,
+		},
+	})
+}
+
+func TestLintReportsStringTemplates(t *testing.T) {
+	runLintCases(t, []lintCase{
+		{
+			name:                "expansion in double quotes",
+			expression:          `build.branch == "release-${VERSION:-$DEFAULT}"`,
+			wantCodes:           []string{diagnosticStringTemplate},
+			wantMessageContains: "expands $VERSION, $DEFAULT from the environment at runtime",
+		},
+		{
+			name:       "single quotes are literal",
+			expression: `build.branch == 'release-$VERSION'`,
+		},
+		{
+			name:                "escaped dollar is rewritten",
+			expression:          `build.message == "costs $$5"`,
+			wantCodes:           []string{diagnosticStringTemplate},
+			wantMessageContains: `"costs $$5" rewrites $$ to a literal $ at runtime`,
+		},
+	})
+
+	diagnostics := Lint(`build.message == "$HOME"`, Context{})
+	if len(diagnostics) != 1 || diagnostics[0].Severity != SeverityInfo {
+		t.Fatalf("Lint = %v, want one info diagnostic", diagnostics)
+	}
+}
+
+func TestLintShellExpansions(t *testing.T) {
+	runLintCases(t, []lintCase{
+		{
+			name:                "unsupported Buildkite variable",
+			expression:          `$BUILDKITE_BRANC == "main"`,
+			wantCodes:           []string{diagnosticUnsupportedEnv},
+			wantMessageContains: "did you mean BUILDKITE_BRANCH?",
+		},
+		{
+			name:       "runtime Buildkite variable",
+			expression: `$BUILDKITE_PULL_REQUEST_LABELS == "deploy"`,
+		},
+		{
+			name:                "non-integer substring offset",
+			expression:          `${COMMIT:a:3} == "abc"`,
+			wantCodes:           []string{diagnosticSubstringOffset},
+			wantMessageContains: `non-integer substring offset "a"`,
+		},
+		{
+			name:                "required expansion",
+			expression:          `${DEPLOY_ENV:?} == "production"`,
+			wantCodes:           []string{diagnosticRequiredExpansion},
+			wantMessageContains: "fails evaluation when DEPLOY_ENV is unset or empty",
+		},
+		{
+			name:                "nested in a double-quoted string",
+			expression:          `build.branch == "release-${VERSION?}"`,
+			wantCodes:           []string{diagnosticRequiredExpansion, diagnosticStringTemplate},
+			wantMessageContains: "fails evaluation when VERSION is unset",
+		},
+		{
+			name:                "enumeration fallback",
+			expression:          `build.state == ${STATE:-pased}`,
+			wantCodes:           []string{diagnosticInvalidDefault},
+			wantMessageContains: "falls back to \"pased\", which is not a valid `build.state`",
+		},
+		{
+			name:       "valid enumeration fallback",
+			expression: `build.state != "${STATE:-passed}"`,
+			wantCodes:  []string{diagnosticStringTemplate},
+		},
+		{
+			name:       "plain expansions",
+			expression: `${BRANCH:-main} == "main" && ${COMMIT:0:7} == "abcdef1"`,
+		},
+	})
+}
+
+func TestLintReportsInvalidExpression(t *testing.T) {
+	diagnostics := Lint(`build.brnach == "main"`, Context{})
+	if len(diagnostics) != 1 {
+		t.Fatalf("Lint returned %d diagnostics, want 1: %v", len(diagnostics), diagnostics)
+	}
+	if diagnostics[0].Severity != SeverityError || diagnostics[0].Code != diagnosticInvalid {
+		t.Fatalf("Lint diagnostic = %+v, want invalid error", diagnostics[0])
+	}
+	if !strings.Contains(diagnostics[0].Message, "is not a variable") {
+		t.Fatalf("Lint diagnostic message = %q, want validation message", diagnostics[0].Message)
+	}
+}
+
+func TestEvaluatorLintUsesOptions(t *testing.T) {
+	evaluator, err := NewEvaluator(WithDialect(Extended))
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	diagnostics := evaluator.Lint(`build.branch in ["main"] && build.branch == "dev"`, Context{})
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnosticAlwaysFalse {
+		t.Fatalf("Evaluator.Lint = %v, want one always-false diagnostic", diagnostics)
+	}
+}
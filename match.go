@@ -0,0 +1,44 @@
+package conditional
+
+import (
+	"fmt"
+
+	"github.com/buildkite/conditional/internal/object"
+	"github.com/buildkite/conditional/internal/regex"
+)
+
+// matchFunction implements match(string, regexp), returning the regexp's
+// capture groups as a string array, or null when the string is null or does
+// not match. Groups that did not take part in the match are empty strings.
+func matchFunction(args []object.Object) object.Object {
+	if len(args) != 2 {
+		return &object.Error{Message: fmt.Sprintf("wrong number of arguments for match: got %d, want 2", len(args))}
+	}
+	re, ok := args[1].(*object.Regexp)
+	if !ok {
+		return &object.Error{Message: fmt.Sprintf("match pattern must be a regular expression, got %s", args[1].Type())}
+	}
+
+	var value string
+	switch arg := args[0].(type) {
+	case *object.Null:
+		return &object.Null{}
+	case *object.String:
+		value = arg.Value
+	default:
+		return &object.Error{Message: fmt.Sprintf("match subject must be a string, got %s", arg.Type())}
+	}
+
+	submatches, err := regex.FindStringSubmatch(re.Regexp, value)
+	if err != nil {
+		return &object.Error{Message: fmt.Sprintf("regexp match failed: %s", err), Cause: err}
+	}
+	if submatches == nil {
+		return &object.Null{}
+	}
+	captures := make([]object.Object, 0, len(submatches)-1)
+	for _, capture := range submatches[1:] {
+		captures = append(captures, &object.String{Value: capture})
+	}
+	return &object.Array{Elements: captures}
+}
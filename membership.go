@@ -0,0 +1,65 @@
+package conditional
+
+import (
+	"fmt"
+
+	"github.com/buildkite/conditional/internal/object"
+)
+
+func intersects(values, candidates map[string]struct{}) bool {
+	for candidate := range candidates {
+		if _, ok := values[candidate]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func allOf(values, candidates map[string]struct{}) bool {
+	for candidate := range candidates {
+		if _, ok := values[candidate]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayPredicateFunction adapts a predicate over two string sets to a
+// conditional function. A null array argument makes the predicate false, the
+// same way includes treats a null array.
+func arrayPredicateFunction(name string, predicate func(values, candidates map[string]struct{}) bool) object.Function {
+	return func(args []object.Object) object.Object {
+		if len(args) != 2 {
+			return &object.Error{Message: fmt.Sprintf("wrong number of arguments for %s: got %d, want 2", name, len(args))}
+		}
+
+		sets := make([]map[string]struct{}, 0, len(args))
+		for _, arg := range args {
+			switch arg := arg.(type) {
+			case *object.Null:
+				return &object.Boolean{Value: false}
+			case *object.Array:
+				set, err := stringElementSet(name, arg)
+				if err != nil {
+					return err
+				}
+				sets = append(sets, set)
+			default:
+				return &object.Error{Message: fmt.Sprintf("%s arguments must be string arrays, got %s", name, arg.Type())}
+			}
+		}
+		return &object.Boolean{Value: predicate(sets[0], sets[1])}
+	}
+}
+
+func stringElementSet(name string, array *object.Array) (map[string]struct{}, *object.Error) {
+	set := make(map[string]struct{}, len(array.Elements))
+	for idx, element := range array.Elements {
+		value, ok := element.(*object.String)
+		if !ok {
+			return nil, &object.Error{Message: fmt.Sprintf("%s: type mismatch at index %d in array: %s vs STRING", name, idx, element.Type())}
+		}
+		set[value.Value] = struct{}{}
+	}
+	return set, nil
+}
@@ -1,8 +1,12 @@
 package conditional
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/buildkite/conditional/internal/object"
 	"github.com/buildkite/conditional/internal/regex"
@@ -12,13 +16,26 @@ import (
 type Option func(*optionSet) error
 
 type optionSet struct {
-	functions map[string]Function
+	functions      map[string]Function
+	variables      map[string]variable
+	dialect        Dialect
+	literalStrings bool
+	limits         limits
+	regexCache     *regex.Cache
+	envSources     []EnvSource
+	redactions     []string
+	envPolicy      *EnvPolicy
+}
+
+type variable struct {
+	typ     ValueType
+	resolve func(Context) Value
 }
 
 // Evaluator validates and evaluates conditionals with reusable options.
 //
-// The zero value is a Buildkite-parity evaluator with no caller-owned
-// functions.
+// The zero value is a Buildkite-parity evaluator using the BuildkiteServer
+// dialect with no caller-owned functions or variables.
 type Evaluator struct {
 	options optionSet
 }
@@ -32,6 +49,14 @@ func NewEvaluator(opts ...Option) (Evaluator, error) {
 	return Evaluator{options: options}, nil
 }
 
+// Dialect returns the conditional language accepted by the evaluator.
+func (e Evaluator) Dialect() Dialect {
+	if e.options.dialect == "" {
+		return BuildkiteServer
+	}
+	return e.options.dialect
+}
+
 // Validate parses expression for the selected Buildkite context using the
 // evaluator's options.
 func (e Evaluator) Validate(expression string, ctx Context) error {
@@ -59,7 +84,8 @@ type Function struct {
 	Eval   func(args []Value) (Value, error)
 }
 
-// ValueType describes a conditional value type.
+// ValueType describes a conditional value type. Value types are comparable:
+// EnumType returns equal types for equal names and value sets.
 type ValueType string
 
 const (
@@ -77,6 +103,37 @@ const (
 	StringArrayType ValueType = "string array"
 )
 
+// errInvalidValueType reports a ValueType that is neither predefined nor
+// returned by EnumType.
+var errInvalidValueType = errors.New("invalid value type")
+
+// enumTypes maps each ValueType returned by EnumType to its *enumTypeSpec, so
+// that only EnumType can create enumerations.
+var enumTypes sync.Map
+
+type enumTypeSpec struct {
+	name   string
+	values []string
+}
+
+// EnumType returns a string type restricted to values. Static string literals
+// compared with, or passed as, an enum-typed value are validated against
+// values, matching Buildkite assignments such as build.state. name describes
+// the enumeration in error messages.
+func EnumType(name string, values ...string) ValueType {
+	spec := &enumTypeSpec{name: name, values: slices.Sorted(slices.Values(values))}
+	var key strings.Builder
+	key.WriteString("enum ")
+	key.WriteString(strconv.Quote(name))
+	for _, value := range spec.values {
+		key.WriteByte(' ')
+		key.WriteString(strconv.Quote(value))
+	}
+	typ := ValueType(key.String())
+	enumTypes.LoadOrStore(typ, spec)
+	return typ
+}
+
 // String returns a human-readable value type description.
 func (t ValueType) String() string {
 	typ, err := t.internal()
@@ -100,9 +157,28 @@ func (t ValueType) internal() (valueType, error) {
 		return valueType{kind: kindRegexp}, nil
 	case StringArrayType:
 		return stringArrayType(), nil
-	default:
-		return valueType{kind: kindUnknown}, validationError("invalid function value type")
 	}
+	if spec, ok := enumTypes.Load(t); ok {
+		return spec.(*enumTypeSpec).valueType()
+	}
+	return valueType{kind: kindUnknown}, errInvalidValueType
+}
+
+func (spec *enumTypeSpec) valueType() (valueType, error) {
+	if strings.TrimSpace(spec.name) == "" {
+		return valueType{kind: kindUnknown}, validationError("enum type name is required")
+	}
+	if len(spec.values) == 0 {
+		return valueType{kind: kindUnknown}, validationError("enum type `%s` requires at least one value", spec.name)
+	}
+	seen := make(map[string]struct{}, len(spec.values))
+	for _, value := range spec.values {
+		if _, ok := seen[value]; ok {
+			return valueType{kind: kindUnknown}, validationError("enum type `%s` repeats value %q", spec.name, value)
+		}
+		seen[value] = struct{}{}
+	}
+	return enumValueType(spec.name, spec.values...), nil
 }
 
 // Value is a conditional runtime value.
@@ -251,10 +327,10 @@ func valueFromObject(obj object.Object) Value {
 // WithFunction registers an opt-in conditional function.
 func WithFunction(name string, function Function) Option {
 	return func(options *optionSet) error {
-		if err := validateFunctionName(name); err != nil {
+		if err := validateName("function", name); err != nil {
 			return err
 		}
-		if reservedFunctionName(name) {
+		if reservedName(name) {
 			return validationError("function `%s` uses a reserved Buildkite name", name)
 		}
 		if function.Eval == nil {
@@ -268,7 +344,7 @@ func WithFunction(name string, function Function) Option {
 		if options.functions == nil {
 			options.functions = map[string]Function{}
 		}
-		if _, ok := options.functions[name]; ok {
+		if options.registered(name) {
 			return validationError("function `%s` is already registered", name)
 		}
 		options.functions[name] = function
@@ -276,6 +352,66 @@ func WithFunction(name string, function Function) Option {
 	}
 }
 
+// WithVariable registers an opt-in conditional variable. The resolver is called
+// with the evaluation Context and must return a value of typ or null. It is
+// called at most once per evaluation, when the expression first reads the
+// variable.
+func WithVariable(name string, typ ValueType, resolve func(Context) Value) Option {
+	return func(options *optionSet) error {
+		if err := validateName("variable", name); err != nil {
+			return err
+		}
+		if reservedName(name) {
+			return validationError("variable `%s` uses a reserved Buildkite name", name)
+		}
+		if resolve == nil {
+			return validationError("variable `%s` requires a resolver", name)
+		}
+		if _, err := typ.internal(); errors.Is(err, errInvalidValueType) {
+			return validationError("variable `%s` has an invalid value type", name)
+		} else if err != nil {
+			return err
+		}
+
+		if options.variables == nil {
+			options.variables = map[string]variable{}
+		}
+		if options.registered(name) {
+			return validationError("variable `%s` is already registered", name)
+		}
+		options.variables[name] = variable{typ: typ, resolve: resolve}
+		return nil
+	}
+}
+
+// validate checks option combinations that individual options cannot see.
+func (o optionSet) validate() error {
+	if !o.extended() {
+		return nil
+	}
+	for name := range extendedFunctionTypes {
+		if o.registered(name) {
+			return validationError("`%s` is reserved by the %s dialect", name, Extended)
+		}
+	}
+	// in is an operator only in the Extended dialect, so BuildkiteServer
+	// callers may still register it.
+	if o.registered("in") {
+		return validationError("`in` is an operator in the %s dialect", Extended)
+	}
+	return nil
+}
+
+// registered reports whether name is already used by a caller-owned function
+// or variable. Both share the evaluation scope, so names must be unique.
+func (o optionSet) registered(name string) bool {
+	if _, ok := o.functions[name]; ok {
+		return true
+	}
+	_, ok := o.variables[name]
+	return ok
+}
+
 func applyOptions(opts []Option) (optionSet, error) {
 	var options optionSet
 	for _, opt := range opts {
@@ -286,20 +422,23 @@ func applyOptions(opts []Option) (optionSet, error) {
 			return optionSet{}, err
 		}
 	}
+	if err := options.validate(); err != nil {
+		return optionSet{}, err
+	}
 	return options, nil
 }
 
 func (f Function) signature() (functionSignature, error) {
-	args := make([]valueKind, 0, len(f.Args))
+	args := make([]valueType, 0, len(f.Args))
 	for _, arg := range f.Args {
-		typ, err := arg.internal()
+		typ, err := functionValueType(arg)
 		if err != nil {
 			return functionSignature{}, err
 		}
-		args = append(args, typ.kind)
+		args = append(args, typ)
 	}
 
-	ret, err := f.Return.internal()
+	ret, err := functionValueType(f.Return)
 	if err != nil {
 		return functionSignature{}, err
 	}
@@ -307,7 +446,16 @@ func (f Function) signature() (functionSignature, error) {
 	return functionSignature{args: args, ret: ret}, nil
 }
 
+func functionValueType(t ValueType) (valueType, error) {
+	typ, err := t.internal()
+	if errors.Is(err, errInvalidValueType) {
+		return typ, validationError("invalid function value type")
+	}
+	return typ, err
+}
+
 func (f Function) objectFunction(name string) object.Function {
+	ret, _ := f.Return.internal()
 	return func(args []object.Object) object.Object {
 		values := make([]Value, 0, len(args))
 		for _, arg := range args {
@@ -316,15 +464,15 @@ func (f Function) objectFunction(name string) object.Function {
 
 		result, err := f.Eval(values)
 		if err != nil {
-			return &object.Error{Message: err.Error()}
+			return &object.Error{Message: err.Error(), Cause: err}
 		}
-		if !resultMatchesType(result, f.Return) {
+		if !resultMatchesType(result, ret) {
 			return &object.Error{
 				Message: fmt.Sprintf(
 					"function %s returned %s, want %s",
 					name,
-					result.Type(),
-					f.Return,
+					describeResult(result, ret),
+					ret.describe(),
 				),
 			}
 		}
@@ -332,44 +480,75 @@ func (f Function) objectFunction(name string) object.Function {
 	}
 }
 
-func resultMatchesType(value Value, typ ValueType) bool {
+func (v variable) object(name string, ctx Context) object.Object {
+	typ, _ := v.typ.internal()
+	result := v.resolve(ctx)
+	if !resultMatchesType(result, typ) {
+		return &object.Error{
+			Message: fmt.Sprintf(
+				"variable %s returned %s, want %s",
+				name,
+				describeResult(result, typ),
+				typ.describe(),
+			),
+		}
+	}
+	return result.object()
+}
+
+func resultMatchesType(value Value, typ valueType) bool {
 	if value.IsNull() {
 		return true
 	}
-	return value.Type() == typ
+	if typ.enum != nil {
+		str, ok := value.AsString()
+		return ok && typ.enum.includes(str)
+	}
+	return value.Type() == ValueType(typ.kind)
+}
+
+// describeResult names the returned value's type, or the value itself when
+// a string falls outside an expected enumeration.
+func describeResult(value Value, typ valueType) string {
+	if typ.enum != nil && value.Type() == StringType {
+		return value.String()
+	}
+	return string(value.Type())
 }
 
-func validateFunctionName(name string) error {
+// validateName checks that name lexes as a single identifier. kind names the
+// registered value in error messages.
+func validateName(kind string, name string) error {
 	if name == "" {
-		return validationError("function name is required")
+		return validationError("%s name is required", kind)
 	}
 	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
-		return validationError("invalid function name `%s`", name)
+		return validationError("invalid %s name `%s`", kind, name)
 	}
 	switch name {
 	case "true", "false", "null", "includes":
-		return validationError("invalid function name `%s`", name)
+		return validationError("invalid %s name `%s`", kind, name)
 	}
-	if !isFunctionNameStart(name[0]) {
-		return validationError("invalid function name `%s`", name)
+	if !isNameStart(name[0]) {
+		return validationError("invalid %s name `%s`", kind, name)
 	}
 	for i := 1; i < len(name); i++ {
-		if !isFunctionNamePart(name[i]) {
-			return validationError("invalid function name `%s`", name)
+		if !isNamePart(name[i]) {
+			return validationError("invalid %s name `%s`", kind, name)
 		}
 	}
 	return nil
 }
 
-func isFunctionNameStart(ch byte) bool {
+func isNameStart(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
 
-func isFunctionNamePart(ch byte) bool {
-	return isFunctionNameStart(ch) || '0' <= ch && ch <= '9' || ch == '.'
+func isNamePart(ch byte) bool {
+	return isNameStart(ch) || '0' <= ch && ch <= '9' || ch == '.'
 }
 
-func reservedFunctionName(name string) bool {
+func reservedName(name string) bool {
 	root := name
 	if idx := strings.IndexByte(name, '.'); idx >= 0 {
 		root = name[:idx]
@@ -281,3 +281,386 @@ func TestCustomFunctionReturnTypeValidation(t *testing.T) {
 		t.Fatalf("Evaluate error = %v, want return type message", err)
 	}
 }
+
+func TestCustomVariableOption(t *testing.T) {
+	oncall := WithVariable("team.oncall", StringType, func(ctx Context) Value {
+		return StringValue("alice")
+	})
+
+	expression := `team.oncall == "alice"`
+	if err := Validate(expression, Context{}, oncall); err != nil {
+		t.Fatalf("Validate(%q) returned error: %v", expression, err)
+	}
+
+	got, err := Evaluate(expression, Context{}, oncall)
+	if err != nil {
+		t.Fatalf("Evaluate(%q) returned error: %v", expression, err)
+	}
+	if !got {
+		t.Fatalf("Evaluate(%q) = false, want true", expression)
+	}
+}
+
+func TestCustomVariableResolvesFromContext(t *testing.T) {
+	monorepo := WithVariable("repo.is_monorepo", BoolType, func(ctx Context) Value {
+		return BoolValue(stringPtrValue(ctx.Pipeline.Slug) == "monorepo")
+	})
+
+	got, err := Evaluate(`repo.is_monorepo`, Context{Pipeline: Pipeline{Slug: str("monorepo")}}, monorepo)
+	if err != nil {
+		t.Fatalf("Evaluate returned error: %v", err)
+	}
+	if !got {
+		t.Fatal("Evaluate = false, want true")
+	}
+}
+
+func TestCustomVariablesResolveLazily(t *testing.T) {
+	calls := map[string]int{}
+	resolver := func(name string, value Value) func(Context) Value {
+		return func(Context) Value {
+			calls[name]++
+			return value
+		}
+	}
+	evaluator, err := NewEvaluator(
+		WithVariable("team.oncall", StringType, resolver("team.oncall", StringValue("alice"))),
+		WithVariable("team.size", NumberType, resolver("team.size", NumberValue(4))),
+	)
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	expression := `team.oncall == "alice" && team.oncall != "bob"`
+	got, err := evaluator.Evaluate(expression, Context{})
+	if err != nil {
+		t.Fatalf("Evaluate(%q) returned error: %v", expression, err)
+	}
+	if !got {
+		t.Fatalf("Evaluate(%q) = false, want true", expression)
+	}
+	if calls["team.oncall"] != 1 {
+		t.Fatalf("team.oncall resolver called %d times, want 1", calls["team.oncall"])
+	}
+	if calls["team.size"] != 0 {
+		t.Fatalf("unused team.size resolver called %d times, want 0", calls["team.size"])
+	}
+}
+
+func TestCustomVariableTypeValidation(t *testing.T) {
+	oncall := WithVariable("team.oncall", StringType, func(ctx Context) Value {
+		return StringValue("alice")
+	})
+
+	err := Validate(`team.oncall == 1`, Context{}, oncall)
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindValidation)
+	}
+
+	err = Validate(`team.oncall`, Context{}, oncall)
+	if !IsErrorKind(err, ErrorKindResult) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindResult)
+	}
+}
+
+func TestCustomVariableNullValue(t *testing.T) {
+	oncall := WithVariable("team.oncall", StringType, func(ctx Context) Value {
+		return NullValue()
+	})
+
+	got, err := Evaluate(`team.oncall == null`, Context{}, oncall)
+	if err != nil {
+		t.Fatalf("Evaluate returned error: %v", err)
+	}
+	if !got {
+		t.Fatal("Evaluate = false, want true")
+	}
+}
+
+func TestCustomVariableResolverTypeMismatch(t *testing.T) {
+	oncall := WithVariable("team.oncall", StringType, func(ctx Context) Value {
+		return NumberValue(1)
+	})
+
+	_, err := Evaluate(`team.oncall == "alice"`, Context{}, oncall)
+	if !IsErrorKind(err, ErrorKindEvaluation) {
+		t.Fatalf("Evaluate error = %v, want %s", err, ErrorKindEvaluation)
+	}
+	if !strings.Contains(err.Error(), "returned number, want string") {
+		t.Fatalf("Evaluate error = %v, want return type message", err)
+	}
+}
+
+func TestCustomVariableOptionValidation(t *testing.T) {
+	resolve := func(ctx Context) Value { return StringValue("x") }
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "reserved build namespace",
+			opts: []Option{WithVariable("build.custom", StringType, resolve)},
+		},
+		{
+			name: "invalid variable name",
+			opts: []Option{WithVariable("custom.", StringType, resolve)},
+		},
+		{
+			name: "keyword variable name",
+			opts: []Option{WithVariable("null", StringType, resolve)},
+		},
+		{
+			name: "missing resolver",
+			opts: []Option{WithVariable("custom", StringType, nil)},
+		},
+		{
+			name: "invalid type",
+			opts: []Option{WithVariable("custom", ValueType("object"), resolve)},
+		},
+		{
+			name: "duplicate variable",
+			opts: []Option{
+				WithVariable("custom", StringType, resolve),
+				WithVariable("custom", StringType, resolve),
+			},
+		},
+		{
+			name: "function name conflict",
+			opts: []Option{
+				WithFunction("custom", Function{
+					Return: BoolType,
+					Eval: func(args []Value) (Value, error) {
+						return BoolValue(true), nil
+					},
+				}),
+				WithVariable("custom", StringType, resolve),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEvaluator(tt.opts...)
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+			}
+		})
+	}
+}
+
+func TestEnumTypeVariableValidatesLiterals(t *testing.T) {
+	environment := WithVariable("deploy.environment", EnumType("deploy environment", "staging", "production"), func(ctx Context) Value {
+		return StringValue("staging")
+	})
+
+	if err := Validate(`deploy.environment == "staging"`, Context{}, environment); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+
+	err := Validate(`deploy.environment == "prod"`, Context{}, environment)
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindValidation)
+	}
+	if !strings.Contains(err.Error(), "\"prod\" is not a valid `deploy.environment`") {
+		t.Fatalf("Validate error = %v, want enum message", err)
+	}
+}
+
+func TestEnumTypeFunctionArgument(t *testing.T) {
+	deployTo := WithFunction("deploy_to", Function{
+		Args:   []ValueType{EnumType("deploy environment", "staging", "production")},
+		Return: BoolType,
+		Eval: func(args []Value) (Value, error) {
+			value, _ := args[0].AsString()
+			return BoolValue(value == "production"), nil
+		},
+	})
+
+	got, err := Evaluate(`deploy_to("production")`, Context{}, deployTo)
+	if err != nil {
+		t.Fatalf("Evaluate returned error: %v", err)
+	}
+	if !got {
+		t.Fatal("Evaluate = false, want true")
+	}
+
+	err = Validate(`deploy_to("prod")`, Context{}, deployTo)
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindValidation)
+	}
+	if !strings.Contains(err.Error(), `"prod" is not a valid deploy environment`) {
+		t.Fatalf("Validate error = %v, want enum message", err)
+	}
+
+	err = Validate(`deploy_to(build.state)`, Context{}, deployTo)
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindValidation)
+	}
+}
+
+func TestEnumTypeFunctionArgumentRequiresMemberSubset(t *testing.T) {
+	deployTo := WithFunction("deploy_to", Function{
+		Args:   []ValueType{EnumType("deploy environment", "production")},
+		Return: BoolType,
+		Eval: func(args []Value) (Value, error) {
+			return BoolValue(true), nil
+		},
+	})
+	variable := func(values ...string) Option {
+		return WithVariable("deploy.environment", EnumType("deploy environment", values...), func(Context) Value {
+			return StringValue(values[0])
+		})
+	}
+
+	err := Validate(`deploy_to(deploy.environment)`, Context{}, deployTo, variable("staging", "production"))
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindValidation)
+	}
+	if !strings.Contains(err.Error(), `which also allows "staging"`) {
+		t.Fatalf("Validate error = %v, want the member outside the argument type", err)
+	}
+
+	if err := Validate(`deploy_to(deploy.environment)`, Context{}, deployTo, variable("production")); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+}
+
+func TestEnumTypeReturnValueValidation(t *testing.T) {
+	target := WithFunction("deploy_target", Function{
+		Return: EnumType("deploy environment", "staging", "production"),
+		Eval: func(args []Value) (Value, error) {
+			return StringValue("prod"), nil
+		},
+	})
+
+	if err := Validate(`deploy_target() == "pre"`, Context{}, target); !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindValidation)
+	}
+
+	_, err := Evaluate(`deploy_target() == "production"`, Context{}, target)
+	if !IsErrorKind(err, ErrorKindEvaluation) {
+		t.Fatalf("Evaluate error = %v, want %s", err, ErrorKindEvaluation)
+	}
+	if !strings.Contains(err.Error(), `returned "prod", want deploy environment enumeration value`) {
+		t.Fatalf("Evaluate error = %v, want enum return message", err)
+	}
+}
+
+func TestEnumTypeOptionValidation(t *testing.T) {
+	resolve := func(ctx Context) Value { return StringValue("x") }
+	tests := []struct {
+		name string
+		typ  ValueType
+	}{
+		{name: "missing name", typ: EnumType("", "x")},
+		{name: "missing values", typ: EnumType("deploy environment")},
+		{name: "duplicate values", typ: EnumType("deploy environment", "x", "x")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEvaluator(WithVariable("custom", tt.typ, resolve))
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+			}
+		})
+	}
+}
+
+func TestInvalidValueTypeMessages(t *testing.T) {
+	tests := []struct {
+		option Option
+		want   string
+	}{
+		{
+			option: WithVariable("custom", ValueType("object"), func(Context) Value { return Value{} }),
+			want:   "variable `custom` has an invalid value type",
+		},
+		{
+			option: WithFunction("custom", Function{
+				Args:   []ValueType{"object"},
+				Return: BoolType,
+				Eval:   func(args []Value) (Value, error) { return BoolValue(true), nil },
+			}),
+			want: "invalid function value type",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := NewEvaluator(tt.option)
+		if !IsErrorKind(err, ErrorKindValidation) {
+			t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("NewEvaluator error = %q, want it to contain %q", err, tt.want)
+		}
+	}
+}
+
+func TestEnumTypesCompareByNameAndValues(t *testing.T) {
+	production := EnumType("deploy environment", "staging", "production")
+	if production != EnumType("deploy environment", "production", "staging") {
+		t.Fatal("EnumType with the same name and values returned unequal types")
+	}
+	if production == EnumType("deploy environment", "staging") || production == EnumType("environment", "staging", "production") {
+		t.Fatal("EnumType with a different name or values returned an equal type")
+	}
+	if production == StringType {
+		t.Fatal("EnumType returned StringType")
+	}
+}
+
+func TestHandWrittenEnumTypesAreInvalid(t *testing.T) {
+	typ := ValueType(string(EnumType("deploy environment", "staging")) + ` "production"`)
+	_, err := NewEvaluator(WithVariable("deploy.environment", typ, func(Context) Value { return Value{} }))
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+	}
+	if got := typ.String(); got != "invalid" {
+		t.Fatalf("String() = %q, want invalid", got)
+	}
+}
+
+func TestEnumTypeString(t *testing.T) {
+	if got := EnumType("deploy environment", "staging").String(); got != "deploy environment enumeration value" {
+		t.Fatalf("String() = %q, want enumeration description", got)
+	}
+}
+
+func TestWithLiteralStringsRejectsStringTemplates(t *testing.T) {
+	ctx := Context{
+		EntryPoint: EntryPointBuildCondition,
+		Build:      Build{Branch: str("release-1")},
+		BuildEnv:   map[string]string{"VERSION": "1"},
+	}
+
+	if ok, err := Evaluate(`build.branch == "release-$VERSION"`, ctx); err != nil || !ok {
+		t.Fatalf("Evaluate without option = %t, %v, want true", ok, err)
+	}
+
+	err := Validate(`build.branch == "release-$VERSION"`, ctx, WithLiteralStrings())
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindValidation)
+	}
+	if !strings.Contains(err.Error(), `"release-$VERSION" expands $VERSION`) {
+		t.Fatalf("Validate error = %v, want expansion message", err)
+	}
+
+	err = Validate(`build.message == "costs $$5"`, ctx, WithLiteralStrings())
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("Validate error = %v, want %s", err, ErrorKindValidation)
+	}
+	if !strings.Contains(err.Error(), `"costs $$5" rewrites $$ to a literal $`) {
+		t.Fatalf("Validate error = %v, want escaped dollar message", err)
+	}
+
+	for _, expression := range []string{
+		`build.branch == 'release-$VERSION'`,
+		`build.message == 'costs $$5'`,
+		`$VERSION == "1"`,
+	} {
+		if err := Validate(expression, ctx, WithLiteralStrings()); err != nil {
+			t.Fatalf("Validate(%q) returned error: %v", expression, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package conditional
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces redacted environment values in error messages.
+const redactedValue = "[REDACTED]"
+
+// WithRedaction keeps secret environment values out of evaluation errors.
+// Values read from environment variables whose names match any of patterns,
+// such as *_TOKEN or *_SECRET, are replaced with [REDACTED] in the messages of
+// returned errors and their causes. Patterns use path.Match syntax.
+func WithRedaction(patterns ...string) Option {
+	return func(options *optionSet) error {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return validationError("invalid redaction pattern %q: %v", pattern, err)
+			}
+		}
+		options.redactions = append(options.redactions, patterns...)
+		return nil
+	}
+}
+
+// redactor records the values an evaluation reads from environment variables
+// matching the redaction patterns. A nil redactor redacts nothing.
+type redactor struct {
+	patterns []string
+	values   []string
+}
+
+func (o optionSet) redactor() *redactor {
+	if len(o.redactions) == 0 {
+		return nil
+	}
+	return &redactor{patterns: o.redactions}
+}
+
+func (r *redactor) record(name string, value string) {
+	if r == nil || value == "" {
+		return
+	}
+	for _, pattern := range r.patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			r.values = append(r.values, value)
+			return
+		}
+	}
+}
+
+func (r *redactor) redact(message string) string {
+	if r == nil || len(r.values) == 0 {
+		return message
+	}
+	// Replace longer values first so a value containing another is redacted
+	// whole.
+	values := append([]string(nil), r.values...)
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		message = strings.ReplaceAll(message, value, redactedValue)
+	}
+	return message
+}
+
+// redactError redacts err's message and replaces its cause with a redacted
+// copy of the cause chain. errors.Is still matches the original causes, but
+// errors.As and errors.Unwrap only reach redacted copies, so the unredacted
+// messages cannot be recovered from the returned error.
+func (r *redactor) redactError(err *Error) *Error {
+	if r == nil || len(r.values) == 0 {
+		return err
+	}
+	err.Message = r.redact(err.Message)
+	if err.Cause != nil {
+		err.Cause = &redactedError{err: err.Cause, redactor: r}
+	}
+	return err
+}
+
+type redactedError struct {
+	err      error
+	redactor *redactor
+}
+
+func (e *redactedError) Error() string {
+	return e.redactor.redact(e.err.Error())
+}
+
+func (e *redactedError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+func (e *redactedError) Unwrap() error {
+	cause := errors.Unwrap(e.err)
+	if cause == nil {
+		return nil
+	}
+	return &redactedError{err: cause, redactor: e.redactor}
+}
@@ -0,0 +1,126 @@
+package conditional
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedactionOption(t *testing.T) {
+	secret := func(args []Value) (Value, error) {
+		value, _ := args[0].AsString()
+		return Value{}, errors.New("rejected token " + value)
+	}
+	ctx := Context{BuildEnv: map[string]string{
+		"DEPLOY_TOKEN":  "tok-123456",
+		"DB_SECRET":     "hunter2",
+		"DEPLOY_TARGET": "production",
+	}}
+
+	tests := []struct {
+		name        string
+		expression  string
+		options     []Option
+		wantMessage string
+		hidden      string
+	}{
+		{
+			name:        "shell substring offset",
+			expression:  `${DEPLOY_TARGET:$DEPLOY_TOKEN} == "prod"`,
+			options:     []Option{WithRedaction("*_TOKEN")},
+			wantMessage: `not "[REDACTED]"`,
+			hidden:      "tok-123456",
+		},
+		{
+			name:        "function error",
+			expression:  `check(env("DB_SECRET"))`,
+			options:     []Option{WithRedaction("*_TOKEN", "*_SECRET")},
+			wantMessage: "rejected token [REDACTED]",
+			hidden:      "hunter2",
+		},
+		{
+			name:        "unmatched names are not redacted",
+			expression:  `check(env("DEPLOY_TARGET"))`,
+			options:     []Option{WithRedaction("*_TOKEN", "*_SECRET")},
+			wantMessage: "rejected token production",
+		},
+		{
+			name:        "no redaction by default",
+			expression:  `check(env("DB_SECRET"))`,
+			wantMessage: "rejected token hunter2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]Option{WithFunction("check", Function{
+				Args:   []ValueType{StringType},
+				Return: BoolType,
+				Eval:   secret,
+			})}, tt.options...)
+			_, err := Evaluate(tt.expression, ctx, options...)
+			if !IsErrorKind(err, ErrorKindEvaluation) {
+				t.Fatalf("Evaluate(%q) error = %v, want %s", tt.expression, err, ErrorKindEvaluation)
+			}
+			if !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Fatalf("Evaluate(%q) error = %q, want it to contain %q", tt.expression, err, tt.wantMessage)
+			}
+			if tt.hidden == "" {
+				return
+			}
+			if cause := errors.Unwrap(err); cause != nil && strings.Contains(cause.Error(), tt.hidden) {
+				t.Fatalf("Evaluate(%q) cause = %v, want it redacted", tt.expression, cause)
+			}
+			if strings.Contains(err.Error(), tt.hidden) {
+				t.Fatalf("Evaluate(%q) error = %q, want %q redacted", tt.expression, err, tt.hidden)
+			}
+		})
+	}
+}
+
+func TestWithRedactionRejectsInvalidPatterns(t *testing.T) {
+	_, err := NewEvaluator(WithRedaction("[*_TOKEN"))
+	if !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+	}
+}
+
+type tokenError struct {
+	token string
+}
+
+func (e *tokenError) Error() string {
+	return "rejected token " + e.token
+}
+
+var errRejected = errors.New("rejected")
+
+func TestRedactionHidesCauseChain(t *testing.T) {
+	ctx := Context{BuildEnv: map[string]string{"DEPLOY_TOKEN": "tok-123456"}}
+	check := WithFunction("check", Function{
+		Args:   []ValueType{StringType},
+		Return: BoolType,
+		Eval: func(args []Value) (Value, error) {
+			value, _ := args[0].AsString()
+			return Value{}, fmt.Errorf("check: %w: %w", errRejected, &tokenError{token: value})
+		},
+	})
+
+	_, err := Evaluate(`check(env("DEPLOY_TOKEN"))`, ctx, check, WithRedaction("*_TOKEN"))
+	if !IsErrorKind(err, ErrorKindEvaluation) {
+		t.Fatalf("Evaluate error = %v, want %s", err, ErrorKindEvaluation)
+	}
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("errors.Is(%v, errRejected) = false, want true", err)
+	}
+	var tokenErr *tokenError
+	if errors.As(err, &tokenErr) {
+		t.Fatalf("errors.As exposed the unredacted cause %q", tokenErr.token)
+	}
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		if strings.Contains(cause.Error(), "tok-123456") {
+			t.Fatalf("cause %q exposes the token", cause)
+		}
+	}
+}
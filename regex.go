@@ -0,0 +1,60 @@
+package conditional
+
+import "github.com/buildkite/conditional/internal/regex"
+
+// defaultRegexCacheSize bounds the regex cache shared by evaluators that do
+// not set WithRegexCache.
+const defaultRegexCacheSize = 512
+
+// defaultRegexCache is shared by every evaluator without WithRegexCache, so a
+// regexp literal is compiled once per process rather than once per parse.
+var defaultRegexCache = regex.NewCache(defaultRegexCacheSize)
+
+// WithRegexCache gives the evaluator its own cache of up to size compiled
+// regular expressions, evicting the least recently used. Size zero disables
+// caching. Without this option, evaluators share a process-wide cache of 512
+// regular expressions.
+func WithRegexCache(size int) Option {
+	return func(options *optionSet) error {
+		if size < 0 {
+			return validationError("regex cache size must not be negative, got %d", size)
+		}
+		options.regexCache = regex.NewCache(size)
+		return nil
+	}
+}
+
+// RegexCacheStats reports regex cache usage.
+type RegexCacheStats struct {
+	// Hits counts regular expressions reused from the cache.
+	Hits uint64
+	// Misses counts regular expressions that had to be compiled.
+	Misses uint64
+	// Entries is the number of cached regular expressions.
+	Entries int
+	// Size is the maximum number of cached regular expressions.
+	Size int
+}
+
+// RegexCacheStats reports usage of the evaluator's regex cache. Evaluators
+// without WithRegexCache report the shared process-wide cache.
+func (e Evaluator) RegexCacheStats() RegexCacheStats {
+	stats := e.options.cache().Stats()
+	return RegexCacheStats{Hits: stats.Hits, Misses: stats.Misses, Entries: stats.Entries, Size: stats.Size}
+}
+
+func (o optionSet) cache() *regex.Cache {
+	if o.regexCache == nil {
+		return defaultRegexCache
+	}
+	return o.regexCache
+}
+
+func (o optionSet) regexCompiler() regex.Compiler {
+	return regex.Compiler{
+		MatchTimeout:     o.limits.matchTimeout,
+		MaxPatternLength: o.limits.maxPatternLength,
+		Cache:            o.cache(),
+		ExtendedFlags:    o.extended(),
+	}
+}
@@ -1,6 +1,9 @@
 package conditional
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestConditionalRegexSemantics(t *testing.T) {
 	tests := []evaluateCase{
@@ -112,6 +115,9 @@ func TestConditionalRegexValidation(t *testing.T) {
 			source:     upstreamConditionalRegexpModel,
 			expression: `"cb" =~ /(?<!a)b/`,
 			wantError:  ErrorKindParse,
+			wantMessageContains: []string{
+				"/(?<!a)b/ uses negative lookbehind `(?<!` at offset 0, which Buildkite does not support",
+			},
 		},
 		{
 			name:       "atomic group fails during parsing",
@@ -160,6 +166,9 @@ func TestConditionalRegexValidation(t *testing.T) {
 			source:     upstreamConditionalRegexpModel,
 			expression: `"group" =~ /(?<name>group)/`,
 			wantError:  ErrorKindParse,
+			wantMessageContains: []string{
+				"use a plain group `(` instead of `(?<name>`",
+			},
 		},
 		{
 			name:       "python named capture fails during parsing",
@@ -220,7 +229,113 @@ func TestConditionalRegexValidation(t *testing.T) {
 			source:     upstreamConditionalRegexpModel,
 			expression: `"ab" =~ /a(?# (?<= literal )b/`,
 		},
+		{
+			name:                "enum regex suggests the matching member",
+			source:              upstreamParserSpec,
+			expression:          `build.state =~ /pass/`,
+			wantError:           ErrorKindValidation,
+			wantMessageContains: []string{"`build.state` cannot be matched with a regular expression", "use `build.state == \"passed\"` instead"},
+		},
+		{
+			name:                "enum negated regex suggests every matching member",
+			source:              upstreamParserSpec,
+			expression:          `build.state !~ /^fail/`,
+			wantError:           ErrorKindValidation,
+			wantMessageContains: []string{"use `build.state != \"failed\" && build.state != \"failing\"` instead"},
+		},
+		{
+			name:                "enum regex matching no member suggests the closest",
+			source:              upstreamParserSpec,
+			expression:          `build.state =~ /^pased$/`,
+			wantError:           ErrorKindValidation,
+			wantMessageContains: []string{"`/^pased$/` matches none of the members of `build state` (blocked, canceled, canceling, creating, failed, failing, not_run, passed, running, scheduled, skipped, started)", "did you mean `build.state == \"passed\"`?"},
+		},
+		{
+			name:                "step enum regex matching no member suggests the closest",
+			source:              upstreamParserSpec,
+			expression:          `step.outcome =~ /hard_fail$/`,
+			ctx:                 Context{EntryPoint: EntryPointStepNotification},
+			wantError:           ErrorKindValidation,
+			wantMessageContains: []string{"`/hard_fail$/` matches none of the members of `step outcome` (errored, hard_failed, neutral, passed, soft_failed)", "did you mean `step.outcome == \"hard_failed\"`?"},
+		},
+		{
+			name:                "enum regex matching no close member",
+			source:              upstreamParserSpec,
+			expression:          `build.source =~ /zzzz/`,
+			wantError:           ErrorKindValidation,
+			wantMessageContains: []string{"`/zzzz/` matches none of the members of `build source` (api, pipeline_trigger, schedule, trigger_job, ui, webhook)"},
+		},
 	}
 
 	runValidateCases(t, tests)
 }
+
+func TestRegexFeatureErrorReportsPositions(t *testing.T) {
+	expression := `build.tag =~ /^v\d+(?<suffix>-rc)?$/`
+
+	err := Validate(expression, Context{})
+	if !IsErrorKind(err, ErrorKindParse) {
+		t.Fatalf("Validate(%q) error = %v, want %s", expression, err, ErrorKindParse)
+	}
+	var featureErr *RegexFeatureError
+	if !errors.As(err, &featureErr) {
+		t.Fatalf("Validate(%q) error = %v, want *RegexFeatureError", expression, err)
+	}
+
+	want := RegexFeatureError{
+		Pattern:    `^v\d+(?<suffix>-rc)?$`,
+		Feature:    "named group `(?<`",
+		Offset:     5,
+		Pos:        19,
+		Suggestion: "use a plain group `(` instead of `(?<suffix>`",
+	}
+	if *featureErr != want {
+		t.Fatalf("RegexFeatureError = %+v, want %+v", *featureErr, want)
+	}
+	if got := expression[featureErr.Pos : featureErr.Pos+3]; got != "(?<" {
+		t.Fatalf("expression at Pos = %q, want %q", got, "(?<")
+	}
+}
+
+func TestRegexCacheOption(t *testing.T) {
+	evaluator, err := NewEvaluator(WithRegexCache(8))
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	ctx := Context{Build: Build{Branch: str("release/1.0")}}
+	expression := `build.branch =~ /^release\// || build.branch =~ /^hotfix\//`
+	for range 3 {
+		got, err := evaluator.Evaluate(expression, ctx)
+		if err != nil {
+			t.Fatalf("Evaluate(%q) returned error: %v", expression, err)
+		}
+		if !got {
+			t.Fatalf("Evaluate(%q) = false, want true", expression)
+		}
+	}
+
+	want := RegexCacheStats{Hits: 4, Misses: 2, Entries: 2, Size: 8}
+	if got := evaluator.RegexCacheStats(); got != want {
+		t.Fatalf("RegexCacheStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRegexCacheIsNotSharedAcrossDialectFlags(t *testing.T) {
+	expression := `build.message =~ /^Fixes/m`
+	ctx := Context{Build: Build{Message: str("Fixes #12")}}
+	if _, err := Evaluate(expression, ctx, WithDialect(Extended)); err != nil {
+		t.Fatalf("Evaluate(%q) with the extended dialect returned error: %v", expression, err)
+	}
+
+	err := Validate(expression, ctx, WithDialect(BuildkiteServer))
+	if !IsErrorKind(err, ErrorKindParse) {
+		t.Fatalf("Validate(%q) error = %v, want %s", expression, err, ErrorKindParse)
+	}
+}
+
+func TestRegexCacheOptionRejectsNegativeSize(t *testing.T) {
+	if _, err := NewEvaluator(WithRegexCache(-1)); !IsErrorKind(err, ErrorKindValidation) {
+		t.Fatalf("NewEvaluator error = %v, want %s", err, ErrorKindValidation)
+	}
+}
@@ -0,0 +1,111 @@
+package conditional
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Suggest returns the Buildkite environment variable, variable or function
+// name closest to name, for "did you mean" hints in editors and linters. It
+// returns "" when name is already known or nothing is close enough.
+func Suggest(name string) string {
+	return Evaluator{}.Suggest(name)
+}
+
+// Suggest returns the name closest to name among the Buildkite environment
+// variables, the Buildkite variables of every entry point, and the functions
+// and variables added by the evaluator's options.
+func (e Evaluator) Suggest(name string) string {
+	if e.options.known(name) {
+		return ""
+	}
+	return suggestName(name, e.options.suggestionNames())
+}
+
+// suggestionNames lists every name Suggest considers, in a stable order so
+// that ties resolve the same way on every call.
+func (o optionSet) suggestionNames() []string {
+	names := slices.Clone(supportedBuildkiteEnvNames)
+	names = append(names, buildkiteVariableNames()...)
+	names = append(names, slices.Sorted(maps.Keys(o.variables))...)
+	return append(names, slices.Sorted(maps.Keys(functionTypes(o)))...)
+}
+
+func (o optionSet) known(name string) bool {
+	return slices.Contains(o.suggestionNames(), name)
+}
+
+// didYouMean completes a validation message with the closest of names, if
+// any is close to name.
+func didYouMean(message string, name string, names []string) string {
+	if suggestion := suggestName(name, names); suggestion != "" {
+		return fmt.Sprintf("%s - did you mean `%s`?", message, suggestion)
+	}
+	return message
+}
+
+// entryPointDescriptions names entry points in validation messages.
+var entryPointDescriptions = map[EntryPoint]string{
+	EntryPointBuildCondition:         "build conditions",
+	EntryPointBuildConditionWithStep: "build conditions with a step",
+	EntryPointBuildNotification:      "build notifications",
+	EntryPointStepNotification:       "step notifications",
+}
+
+// unavailableHint explains that name, or the Buildkite variable it most
+// likely misspells, exists but not at entryPoint. It returns "" when there is
+// nothing to explain.
+func unavailableHint(name string, entryPoint EntryPoint) string {
+	if entryPoint == "" {
+		entryPoint = EntryPointBuildCondition
+	}
+	if available := variableEntryPoints(name); available != nil {
+		if slices.Contains(available, entryPoint) {
+			return ""
+		}
+		return fmt.Sprintf("`%s` is only available in %s", name, describeEntryPoints(available))
+	}
+	suggestion := suggestName(name, buildkiteVariableNames())
+	if suggestion == "" {
+		return ""
+	}
+	available := variableEntryPoints(suggestion)
+	if slices.Contains(available, entryPoint) {
+		return ""
+	}
+	return fmt.Sprintf("did you mean `%s`? It is only available in %s", suggestion, describeEntryPoints(available))
+}
+
+// buildkiteVariableNames lists the Buildkite variables of every entry point.
+func buildkiteVariableNames() []string {
+	definitions := assignmentDefinitions(Context{EntryPoint: EntryPointBuildConditionWithStep})
+	names := make([]string, 0, len(definitions))
+	for _, definition := range definitions {
+		names = append(names, definition.name)
+	}
+	return names
+}
+
+// variableEntryPoints returns the entry points the Buildkite variable name is
+// valid in, or nil when name is not a Buildkite variable.
+func variableEntryPoints(name string) []EntryPoint {
+	for _, definition := range assignmentDefinitions(Context{EntryPoint: EntryPointBuildConditionWithStep}) {
+		if definition.name == name {
+			return definitionEntryPoints(definition)
+		}
+	}
+	return nil
+}
+
+func describeEntryPoints(available []EntryPoint) string {
+	descriptions := make([]string, len(available))
+	for i, entryPoint := range available {
+		descriptions[i] = entryPointDescriptions[entryPoint]
+	}
+	if len(descriptions) == 1 {
+		return descriptions[0]
+	}
+	return strings.Join(descriptions[:len(descriptions)-1], ", ") + " and " + descriptions[len(descriptions)-1]
+}
@@ -0,0 +1,123 @@
+package conditional
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "BUILDKITE_BRNCH", want: "BUILDKITE_BRANCH"},
+		{name: "biuld.branch", want: "build.branch"},
+		{name: "pipline.slug", want: "pipeline.slug"},
+		{name: "step.kye", want: "step.key"},
+		{name: "build.env", want: ""},
+		{name: "build.branch", want: ""},
+		{name: "deployment_window", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Suggest(tt.name); got != tt.want {
+			t.Fatalf("Suggest(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluatorSuggestIncludesOptions(t *testing.T) {
+	evaluator, err := NewEvaluator(
+		WithDialect(Extended),
+		WithFunction("deploy_allowed", Function{Args: []ValueType{StringType}, Return: BoolType, Eval: func([]Value) (Value, error) { return BoolValue(true), nil }}),
+		WithVariable("release.channel", StringType, func(Context) Value { return StringValue("stable") }),
+	)
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "deploy_alowed", want: "deploy_allowed"},
+		{name: "release.chanel", want: "release.channel"},
+		{name: "intersect", want: "intersects"},
+		{name: "deploy_allowed", want: ""},
+	}
+	for _, tt := range tests {
+		if got := evaluator.Suggest(tt.name); got != tt.want {
+			t.Fatalf("Suggest(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidationSuggestsVariablesAndFunctions(t *testing.T) {
+	deployAllowed := WithFunction("deploy_allowed", Function{Args: []ValueType{StringType}, Return: BoolType, Eval: func([]Value) (Value, error) { return BoolValue(true), nil }})
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{expression: `biuld.branch == "main"`, want: "`biuld.branch` is not a variable - did you mean `build.branch`?"},
+		{expression: `deploy_alowed(build.branch)`, want: "`deploy_alowed` is not a function - did you mean `deploy_allowed`?"},
+		{expression: `deployment_window == "open"`, want: "`deployment_window` is not a variable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			err := Validate(tt.expression, Context{}, deployAllowed)
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("Validate(%q) error = %v, want %s", tt.expression, err, ErrorKindValidation)
+			}
+			if !strings.HasSuffix(err.Error(), tt.want) {
+				t.Fatalf("Validate(%q) error = %q, want it to end with %q", tt.expression, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationExplainsEntryPointMistakes(t *testing.T) {
+	tests := []struct {
+		expression string
+		entryPoint EntryPoint
+		want       string
+	}{
+		{
+			expression: `step.outcome == "passed"`,
+			entryPoint: EntryPointBuildNotification,
+			want:       "step variables are not available for entry point \"build_notification\"; `step.outcome` is only available in build conditions with a step and step notifications",
+		},
+		{
+			expression: `step.outcom == "passed"`,
+			entryPoint: EntryPointBuildCondition,
+			want:       "step variables are not available for entry point \"build_condition\"; did you mean `step.outcome`? It is only available in build conditions with a step and step notifications",
+		},
+		{
+			expression: `stepp.outcome == "passed"`,
+			entryPoint: EntryPointBuildNotification,
+			want:       "`stepp.outcome` is not a variable - did you mean `step.outcome`? It is only available in build conditions with a step and step notifications",
+		},
+		{
+			expression: `step.outcom == "passed"`,
+			entryPoint: EntryPointStepNotification,
+			want:       "`step.outcom` is not a variable - did you mean `step.outcome`?",
+		},
+		{
+			expression: `build.brnach == "main"`,
+			entryPoint: EntryPointStepNotification,
+			want:       "`build.brnach` is not a variable - did you mean `build.branch`?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.entryPoint)+" "+tt.expression, func(t *testing.T) {
+			err := Validate(tt.expression, Context{EntryPoint: tt.entryPoint})
+			if !IsErrorKind(err, ErrorKindValidation) {
+				t.Fatalf("Validate(%q) error = %v, want %s", tt.expression, err, ErrorKindValidation)
+			}
+			if !strings.HasSuffix(err.Error(), tt.want) {
+				t.Fatalf("Validate(%q) error = %q, want it to end with %q", tt.expression, err, tt.want)
+			}
+		})
+	}
+}
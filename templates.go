@@ -0,0 +1,70 @@
+package conditional
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buildkite/conditional/internal/ast"
+	"github.com/buildkite/conditional/internal/shell"
+)
+
+// WithLiteralStrings rejects double-quoted strings that shell expansion
+// rewrites, such as "deploy to $ENV" or "costs $$5", for teams that never intend string
+// templating. Standalone shell expansions such as $ENV are still accepted.
+func WithLiteralStrings() Option {
+	return func(options *optionSet) error {
+		options.literalStrings = true
+		return nil
+	}
+}
+
+// validateStringTemplates rejects the first double-quoted string that will be
+// expanded at runtime when WithLiteralStrings is set.
+func validateStringTemplates(expr ast.Expression, options optionSet) error {
+	if !options.literalStrings {
+		return nil
+	}
+	if literals := runtimeStringLiterals(expr); len(literals) > 0 {
+		return validationError("%s", stringTemplateMessage(literals[0]))
+	}
+	return nil
+}
+
+// runtimeStringLiterals returns the double-quoted strings in expr that shell
+// expansion changes at runtime, including strings whose only change is
+// rewriting $$ to $, in source order.
+func runtimeStringLiterals(expr ast.Expression) []*ast.StringLiteral {
+	if literal, ok := expr.(*ast.StringLiteral); ok && literal.Token.Flags == `"` && shell.ContainsTemplate(literalSource(literal)) {
+		return []*ast.StringLiteral{literal}
+	}
+	var literals []*ast.StringLiteral
+	for _, child := range ast.Children(expr) {
+		literals = append(literals, runtimeStringLiterals(child)...)
+	}
+	return literals
+}
+
+func stringTemplateMessage(literal *ast.StringLiteral) string {
+	raw := literalSource(literal)
+	names := shell.References(raw)
+	if len(names) == 0 {
+		return fmt.Sprintf("%q rewrites $$ to a literal $ at runtime; use single quotes if that is not intended", raw)
+	}
+	for i, name := range names {
+		names[i] = "$" + name
+	}
+	return fmt.Sprintf(
+		"%q expands %s from the environment at runtime; use single quotes if that is not intended",
+		raw,
+		strings.Join(names, ", "),
+	)
+}
+
+// literalSource returns the source text of literal, before escapes are
+// processed.
+func literalSource(literal *ast.StringLiteral) string {
+	if literal.Token.Raw == "" {
+		return literal.Value
+	}
+	return literal.Token.Raw
+}
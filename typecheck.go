@@ -2,6 +2,10 @@ package conditional
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/buildkite/conditional/internal/ast"
 	"github.com/buildkite/conditional/internal/evaluator"
@@ -30,20 +34,32 @@ type valueType struct {
 }
 
 type functionSignature struct {
-	args []valueKind
+	args []valueType
 	ret  valueType
+	// nullable marks functions whose implementation accepts null for any
+	// value argument, such as an unset pull request field. Regular
+	// expression arguments are always required.
+	nullable bool
 }
 
 type typeChecker struct {
-	variables map[string]valueType
-	functions map[string]functionSignature
+	entryPoint EntryPoint
+	variables  map[string]valueType
+	functions  map[string]functionSignature
+	// types, when set, records the type of every checked expression.
+	types map[ast.Expression]valueType
 }
 
-func typeCheckExpression(expr ast.Expression, ctx Context, options optionSet) error {
-	checker := typeChecker{
-		variables: variableTypes(ctx),
-		functions: functionTypes(options),
+func newTypeChecker(ctx Context, options optionSet) typeChecker {
+	return typeChecker{
+		entryPoint: ctx.EntryPoint,
+		variables:  variableTypes(ctx, options),
+		functions:  functionTypes(options),
 	}
+}
+
+func typeCheckExpression(expr ast.Expression, ctx Context, options optionSet) error {
+	checker := newTypeChecker(ctx, options)
 
 	got, err := checker.check(expr)
 	if err != nil {
@@ -59,6 +75,14 @@ func typeCheckExpression(expr ast.Expression, ctx Context, options optionSet) er
 }
 
 func (c typeChecker) check(expr ast.Expression) (valueType, error) {
+	typ, err := c.checkExpression(expr)
+	if c.types != nil && err == nil {
+		c.types[expr] = typ
+	}
+	return typ, err
+}
+
+func (c typeChecker) checkExpression(expr ast.Expression) (valueType, error) {
 	switch expr := expr.(type) {
 	case *ast.Boolean:
 		return valueType{kind: kindBool}, nil
@@ -75,7 +99,7 @@ func (c typeChecker) check(expr ast.Expression) (valueType, error) {
 	case *ast.Identifier:
 		typ, ok := c.variables[expr.Value]
 		if !ok {
-			return valueType{kind: kindUnknown}, validationError("`%s` is not a variable", expr.Value)
+			return valueType{kind: kindUnknown}, c.unknownVariable(expr.Value)
 		}
 		return typ, nil
 	case *ast.PrefixExpression:
@@ -104,9 +128,31 @@ func (c typeChecker) check(expr ast.Expression) (valueType, error) {
 	}
 }
 
+// unknownVariable explains an unknown identifier. A Buildkite variable from
+// another entry point is reported as such; otherwise the message suggests the
+// closest variable here, or a close variable from another entry point.
+func (c typeChecker) unknownVariable(name string) error {
+	if variableEntryPoints(name) != nil {
+		if hint := unavailableHint(name, c.entryPoint); hint != "" {
+			return validationError("%s", hint)
+		}
+	}
+	message := fmt.Sprintf("`%s` is not a variable", name)
+	if suggestion := suggestName(name, slices.Sorted(maps.Keys(c.variables))); suggestion != "" {
+		return validationError("%s - did you mean `%s`?", message, suggestion)
+	}
+	if hint := unavailableHint(name, c.entryPoint); hint != "" {
+		return validationError("%s - %s", message, hint)
+	}
+	return validationError("%s", message)
+}
+
 func (c typeChecker) checkInfix(expr *ast.InfixExpression) (valueType, error) {
 	switch expr.Operator {
 	case "=~", "!~":
+		if err := c.checkEnumMatch(expr); err != nil {
+			return valueType{kind: kindUnknown}, err
+		}
 		if err := c.expectAny(expr.Left, kindString, kindNull); err != nil {
 			return valueType{kind: kindUnknown}, err
 		}
@@ -122,6 +168,11 @@ func (c typeChecker) checkInfix(expr *ast.InfixExpression) (valueType, error) {
 			return valueType{kind: kindUnknown}, err
 		}
 		return valueType{kind: kindBool}, nil
+	case "in":
+		if err := c.checkMembership(expr.Left, expr.Right); err != nil {
+			return valueType{kind: kindUnknown}, err
+		}
+		return valueType{kind: kindBool}, nil
 	case "&&", "||":
 		if err := c.expect(expr.Left, kindBool); err != nil {
 			return valueType{kind: kindUnknown}, err
@@ -135,11 +186,87 @@ func (c typeChecker) checkInfix(expr *ast.InfixExpression) (valueType, error) {
 			return valueType{kind: kindUnknown}, err
 		}
 		return valueType{kind: kindBool}, nil
+	case "<", "<=", ">", ">=":
+		if err := c.expect(expr.Left, kindNumber); err != nil {
+			return valueType{kind: kindUnknown}, err
+		}
+		if err := c.expect(expr.Right, kindNumber); err != nil {
+			return valueType{kind: kindUnknown}, err
+		}
+		return valueType{kind: kindBool}, nil
 	default:
 		return valueType{kind: kindUnknown}, validationError("`%s` is not a comparison operator", expr.Operator)
 	}
 }
 
+// checkEnumMatch rejects a regex match against an enumeration, as Buildkite
+// does, and reports which members a static regex would have matched so the
+// condition can be rewritten as an equality check.
+func (c typeChecker) checkEnumMatch(expr *ast.InfixExpression) error {
+	leftType, err := c.check(expr.Left)
+	if err != nil || leftType.enum == nil {
+		return err
+	}
+	re, ok := expr.Right.(*ast.Regexp)
+	if !ok {
+		return nil
+	}
+	matches, err := enumMatches(*leftType.enum, re)
+	if err != nil {
+		return nil
+	}
+
+	message := fmt.Sprintf("`%s` cannot be matched with a regular expression", identifierName(expr.Left))
+	comparison, join := "==", " || "
+	if expr.Operator == "!~" {
+		comparison, join = "!=", " && "
+	}
+	if len(matches) == 0 {
+		message += fmt.Sprintf(
+			", and `%s` matches none of the members of `%s` (%s)",
+			re,
+			leftType.enum.name,
+			strings.Join(leftType.enum.members(), ", "),
+		)
+		if member := suggestEnumMember(*leftType.enum, re); member != "" {
+			message += fmt.Sprintf("; did you mean `%s %s %q`?", expr.Left, comparison, member)
+		}
+		return validationError("%s", message)
+	}
+
+	comparisons := make([]string, 0, len(matches))
+	for _, member := range matches {
+		comparisons = append(comparisons, fmt.Sprintf("%s %s %q", expr.Left, comparison, member))
+	}
+	return validationError("%s; use `%s` instead", message, strings.Join(comparisons, join))
+}
+
+func (c typeChecker) checkMembership(left, right ast.Expression) error {
+	leftType, err := c.check(left)
+	if err != nil {
+		return err
+	}
+	switch leftType.kind {
+	case kindString, kindNull, kindUnknown:
+	default:
+		return validationError("unexpected type: expected string or null but found %s", leftType.describe())
+	}
+	if err := c.expectAny(right, kindStringArray, kindNull); err != nil {
+		return err
+	}
+
+	array, ok := right.(*ast.ArrayLiteral)
+	if !ok || leftType.enum == nil {
+		return nil
+	}
+	for _, element := range array.Elements {
+		if literal, ok := staticStringLiteral(element); ok && !leftType.enum.includes(literal.Value) {
+			return validationError("%q is not a valid `%s`", literal.Value, identifierName(left))
+		}
+	}
+	return nil
+}
+
 func (c typeChecker) checkConditional(expr *ast.ConditionalExpression) (valueType, error) {
 	if err := c.expect(expr.Condition, kindBool); err != nil {
 		return valueType{kind: kindUnknown}, err
@@ -150,7 +277,11 @@ func (c typeChecker) checkConditional(expr *ast.ConditionalExpression) (valueTyp
 func (c typeChecker) checkCall(expr *ast.CallExpression) (valueType, error) {
 	signature, ok := c.functions[expr.Function]
 	if !ok {
-		return valueType{kind: kindUnknown}, validationError("`%s` is not a function", expr.Function)
+		if extendedFunction(expr.Function) {
+			return valueType{kind: kindUnknown}, notSupportedByBuildkite(fmt.Sprintf("the `%s` function", expr.Function))
+		}
+		message := didYouMean(fmt.Sprintf("`%s` is not a function", expr.Function), expr.Function, slices.Sorted(maps.Keys(c.functions)))
+		return valueType{kind: kindUnknown}, validationError("%s", message)
 	}
 	if len(expr.Arguments) != len(signature.args) {
 		return valueType{kind: kindUnknown}, validationError(
@@ -161,29 +292,47 @@ func (c typeChecker) checkCall(expr *ast.CallExpression) (valueType, error) {
 		)
 	}
 	for i, arg := range expr.Arguments {
-		if err := c.expectCallArgument(arg, signature.args[i]); err != nil {
+		if err := c.expectCallArgument(arg, signature.args[i], signature.nullable); err != nil {
 			return valueType{kind: kindUnknown}, err
 		}
 	}
 	return signature.ret, nil
 }
 
-func (c typeChecker) expectCallArgument(expr ast.Expression, expected valueKind) error {
+func (c typeChecker) expectCallArgument(expr ast.Expression, expected valueType, nullable bool) error {
 	actual, err := c.check(expr)
 	if err != nil {
 		return err
 	}
-	if actual.kind == kindUnknown {
+	if actual.kind == kindUnknown || nullable && actual.kind == kindNull && expected.kind != kindRegexp {
 		return nil
 	}
-	if expected == kindString && actual.kind == kindString {
+	if expected.enum != nil {
+		return expectEnumArgument(expr, actual, expected)
+	}
+	if expected.kind == kindString && actual.kind == kindString {
 		return nil
 	}
-	if actual.enum == nil && actual.kind == expected {
+	if actual.enum == nil && actual.kind == expected.kind {
 		return nil
 	}
 
-	return validationError("unexpected type: expected %s but found %s", describeKinds([]valueKind{expected}), actual.describe())
+	return validationError("unexpected type: expected %s but found %s", expected.describe(), actual.describe())
+}
+
+func expectEnumArgument(expr ast.Expression, actual valueType, expected valueType) error {
+	if actual.kind != kindString || actual.enum != nil && actual.enum.name != expected.enum.name {
+		return validationError("unexpected type: expected %s but found %s", expected.describe(), actual.describe())
+	}
+	if actual.enum != nil {
+		if extra, ok := actual.enum.outside(*expected.enum); ok {
+			return validationError("unexpected type: expected %s but found %s, which also allows %q", expected.describe(), actual.describe(), extra)
+		}
+	}
+	if literal, ok := staticStringLiteral(expr); ok && !expected.enum.includes(literal.Value) {
+		return validationError("%q is not a valid %s", literal.Value, expected.enum.name)
+	}
+	return nil
 }
 
 func (c typeChecker) checkComparisonTypes(left, right ast.Expression) (valueType, error) {
@@ -282,12 +431,19 @@ func (c typeChecker) expectIncludesRight(expr ast.Expression) error {
 	return validationError("unexpected type: expected string, regular expression, or null but found %s", actual.describe())
 }
 
-func variableTypes(ctx Context) map[string]valueType {
+func variableTypes(ctx Context, options optionSet) map[string]valueType {
 	definitions := assignmentDefinitions(ctx)
-	variables := make(map[string]valueType, len(definitions))
+	variables := make(map[string]valueType, len(definitions)+len(options.variables))
 	for _, definition := range definitions {
 		variables[definition.name] = definition.typ
 	}
+	for name, variable := range options.variables {
+		typ, err := variable.typ.internal()
+		if err != nil {
+			continue
+		}
+		variables[name] = typ
+	}
 
 	return variables
 }
@@ -295,14 +451,19 @@ func variableTypes(ctx Context) map[string]valueType {
 func functionTypes(options optionSet) map[string]functionSignature {
 	functions := map[string]functionSignature{
 		"env": {
-			args: []valueKind{kindString},
+			args: []valueType{stringType()},
 			ret:  stringType(),
 		},
 		"build.env": {
-			args: []valueKind{kindString},
+			args: []valueType{stringType()},
 			ret:  stringType(),
 		},
 	}
+	if options.extended() {
+		for name, signature := range extendedFunctionTypes {
+			functions[name] = signature
+		}
+	}
 	for name, function := range options.functions {
 		signature, err := function.signature()
 		if err != nil {
@@ -355,11 +516,72 @@ func (t valueType) describe() string {
 	return string(t.kind)
 }
 
+// public returns the ValueType that describes t.
+func (t valueType) public() ValueType {
+	if t.enum != nil {
+		return EnumType(t.enum.name, t.enum.members()...)
+	}
+	return ValueType(t.kind)
+}
+
 func (e enumType) includes(value string) bool {
 	_, ok := e.values[value]
 	return ok
 }
 
+// outside returns the first member of e, in sorted order, that other does not
+// include.
+func (e enumType) outside(other enumType) (string, bool) {
+	for _, member := range e.members() {
+		if !other.includes(member) {
+			return member, true
+		}
+	}
+	return "", false
+}
+
+// enumMatches returns the members of enum that re matches, in sorted order.
+func enumMatches(enum enumType, re *ast.Regexp) ([]string, error) {
+	var matches []string
+	for _, member := range enum.members() {
+		matched, err := re.MatchString(member)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			matches = append(matches, member)
+		}
+	}
+	return matches, nil
+}
+
+// suggestEnumMember returns the member of enum closest to the literal text of
+// re, ignoring `^` and `$` anchors, or "" when no member is close.
+func suggestEnumMember(enum enumType, re *ast.Regexp) string {
+	text := strings.TrimSuffix(strings.TrimPrefix(re.Token.Literal, "^"), "$")
+	if text == "" {
+		return ""
+	}
+
+	best, bestDistance := "", (len(text)+3)/4+1
+	for _, member := range enum.members() {
+		if distance := levenshtein(member, strings.ToLower(text)); distance < bestDistance {
+			best, bestDistance = member, distance
+		}
+	}
+	return best
+}
+
+// members returns the enumeration values in sorted order.
+func (e enumType) members() []string {
+	members := make([]string, 0, len(e.values))
+	for value := range e.values {
+		members = append(members, value)
+	}
+	sort.Strings(members)
+	return members
+}
+
 func validationError(format string, args ...any) *Error {
 	return &Error{Kind: ErrorKindValidation, Message: fmt.Sprintf(format, args...)}
 }
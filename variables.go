@@ -0,0 +1,99 @@
+package conditional
+
+import (
+	"maps"
+	"slices"
+)
+
+// VariableInfo describes a variable available to conditionals.
+type VariableInfo struct {
+	Name string
+	Type ValueType
+	// EnumValues lists the accepted values, in sorted order, when Type is an
+	// enumeration.
+	EnumValues []string
+	// Nullable reports whether the variable can evaluate to null.
+	Nullable    bool
+	Description string
+	// EntryPoints lists every entry point the variable is valid in.
+	EntryPoints []EntryPoint
+}
+
+// entryPoints lists every entry point in documentation order.
+var entryPoints = []EntryPoint{
+	EntryPointBuildCondition,
+	EntryPointBuildConditionWithStep,
+	EntryPointBuildNotification,
+	EntryPointStepNotification,
+}
+
+// Variables describes the Buildkite variables available at entryPoint, in
+// assignment table order. An empty entryPoint selects
+// EntryPointBuildCondition; an unknown entryPoint returns nil.
+func Variables(entryPoint EntryPoint) []VariableInfo {
+	return variables(entryPoint, optionSet{})
+}
+
+// Variables describes the Buildkite variables available at entryPoint,
+// followed by variables added with WithVariable in name order.
+func (e Evaluator) Variables(entryPoint EntryPoint) []VariableInfo {
+	return variables(entryPoint, e.options)
+}
+
+func variables(entryPoint EntryPoint, options optionSet) []VariableInfo {
+	entryPoint, err := normalizeEntryPoint(entryPoint)
+	if err != nil {
+		return nil
+	}
+
+	definitions := assignmentDefinitions(Context{EntryPoint: entryPoint})
+	infos := make([]VariableInfo, 0, len(definitions)+len(options.variables))
+	for _, definition := range definitions {
+		infos = append(infos, variableInfo(definition.name, definition.typ, definition.nullable != "", definition.description, definitionEntryPoints(definition)))
+	}
+	for _, name := range slices.Sorted(maps.Keys(options.variables)) {
+		typ, err := options.variables[name].typ.internal()
+		if err != nil {
+			continue
+		}
+		// Resolvers may return the zero Value, which is null.
+		infos = append(infos, variableInfo(name, typ, true, "", entryPoints))
+	}
+	return infos
+}
+
+func variableInfo(name string, typ valueType, nullable bool, description string, available []EntryPoint) VariableInfo {
+	info := VariableInfo{
+		Name:        name,
+		Type:        typ.public(),
+		Nullable:    nullable,
+		Description: description,
+		EntryPoints: append([]EntryPoint(nil), available...),
+	}
+	if typ.enum != nil {
+		info.EnumValues = typ.enum.members()
+	}
+	return info
+}
+
+func definitionEntryPoints(definition assignmentDefinition) []EntryPoint {
+	if !isStepDefinition(definition) {
+		return entryPoints
+	}
+	available := make([]EntryPoint, 0, len(entryPoints))
+	for _, entryPoint := range entryPoints {
+		if stepAllowed(entryPoint) {
+			available = append(available, entryPoint)
+		}
+	}
+	return available
+}
+
+func isStepDefinition(definition assignmentDefinition) bool {
+	for _, step := range stepAssignmentDefinitions {
+		if step.name == definition.name {
+			return true
+		}
+	}
+	return false
+}
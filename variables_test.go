@@ -0,0 +1,91 @@
+package conditional
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVariablesDescribesAssignments(t *testing.T) {
+	variables := Variables(EntryPointBuildCondition)
+	if len(variables) != len(baseAssignmentDefinitions) {
+		t.Fatalf("Variables returned %d variables, want %d", len(variables), len(baseAssignmentDefinitions))
+	}
+
+	byName := map[string]VariableInfo{}
+	for _, variable := range variables {
+		if variable.Description == "" {
+			t.Errorf("%s has no description", variable.Name)
+		}
+		byName[variable.Name] = variable
+	}
+
+	draft := byName["build.pull_request.draft"]
+	if draft.Type != BoolType || !draft.Nullable {
+		t.Fatalf("build.pull_request.draft = %+v, want nullable boolean", draft)
+	}
+	if !reflect.DeepEqual(draft.EntryPoints, entryPoints) {
+		t.Fatalf("build.pull_request.draft entry points = %v, want %v", draft.EntryPoints, entryPoints)
+	}
+
+	source := byName["build.source"]
+	wantSources := []string{"api", "pipeline_trigger", "schedule", "trigger_job", "ui", "webhook"}
+	if !reflect.DeepEqual(source.EnumValues, wantSources) {
+		t.Fatalf("build.source enum values = %v, want %v", source.EnumValues, wantSources)
+	}
+	if source.Type.String() != "build source enumeration value" || source.Nullable {
+		t.Fatalf("build.source = %+v, want non-nullable build source enumeration", source)
+	}
+
+	if _, ok := byName["step.outcome"]; ok {
+		t.Fatal("Variables(EntryPointBuildCondition) includes step.outcome")
+	}
+}
+
+func TestVariablesIncludesStepVariablesForStepEntryPoints(t *testing.T) {
+	for _, entryPoint := range []EntryPoint{EntryPointBuildConditionWithStep, EntryPointStepNotification} {
+		var outcome *VariableInfo
+		for _, variable := range Variables(entryPoint) {
+			if variable.Name == "step.outcome" {
+				outcome = &variable
+			}
+		}
+		if outcome == nil {
+			t.Fatalf("Variables(%s) does not include step.outcome", entryPoint)
+		}
+		want := []EntryPoint{EntryPointBuildConditionWithStep, EntryPointStepNotification}
+		if !reflect.DeepEqual(outcome.EntryPoints, want) {
+			t.Fatalf("step.outcome entry points = %v, want %v", outcome.EntryPoints, want)
+		}
+	}
+}
+
+func TestVariablesRejectsUnknownEntryPoint(t *testing.T) {
+	if variables := Variables("pipeline_upload"); variables != nil {
+		t.Fatalf("Variables returned %d variables for an unknown entry point", len(variables))
+	}
+}
+
+func TestEvaluatorVariablesIncludesCustomVariables(t *testing.T) {
+	evaluator, err := NewEvaluator(
+		WithVariable("team.oncall", StringType, func(Context) Value { return StringValue("alice") }),
+		WithVariable("deploy.environment", EnumType("deploy environment", "staging", "production"), func(Context) Value { return Value{} }),
+	)
+	if err != nil {
+		t.Fatalf("NewEvaluator returned error: %v", err)
+	}
+
+	variables := evaluator.Variables(EntryPointBuildNotification)
+	custom := variables[len(variables)-2:]
+	if custom[0].Name != "deploy.environment" || custom[1].Name != "team.oncall" {
+		t.Fatalf("custom variables = %+v, want deploy.environment then team.oncall", custom)
+	}
+	if custom[0].Type != EnumType("deploy environment", "staging", "production") || custom[1].Type != StringType {
+		t.Fatalf("custom variable types = %v and %v, want the registered types", custom[0].Type, custom[1].Type)
+	}
+	if !reflect.DeepEqual(custom[0].EnumValues, []string{"production", "staging"}) || !custom[0].Nullable {
+		t.Fatalf("deploy.environment = %+v, want nullable enumeration", custom[0])
+	}
+	if !reflect.DeepEqual(custom[1].EntryPoints, entryPoints) {
+		t.Fatalf("team.oncall entry points = %v, want %v", custom[1].EntryPoints, entryPoints)
+	}
+}